@@ -0,0 +1,108 @@
+package routegroup
+
+import (
+	"log/slog"
+	"net"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// AccessLogOpts configures AccessLog. The zero value logs every request with slog.Default at info level.
+type AccessLogOpts struct {
+	Logger *slog.Logger // logger to write to, slog.Default() if nil
+	Level  slog.Level   // level of the records, info by default
+
+	// Sample decides whether to log the completed request, e.g. to log all errors but only a fraction
+	// of successful requests. All requests are logged if nil.
+	Sample func(r *http.Request, status int) bool
+
+	// Redact lists attributes to log with their values replaced by "[redacted]". Top-level attributes
+	// are referenced by key, e.g. "remote_ip", path values as "path.<name>", e.g. "path.token".
+	Redact []string
+
+	// RequestIDHeader is the request header with the request ID, "X-Request-Id" by default.
+	// It's used only for requests without an ID assigned by the RequestID middleware.
+	RequestIDHeader string
+}
+
+const redacted = "[redacted]"
+
+// AccessLog makes a middleware logging completed requests with log/slog. Each record has method,
+// pattern, status, bytes, duration, remote_ip, request_id and a "path" group with the path values
+// of the matched route. The route pattern is logged instead of the raw URL path. The middleware is
+// named "access_log" and is meant to be a root middleware; path values are captured from the matched
+// route, so they are logged even though root middlewares run before routing. Unmatched requests are
+// logged with an empty pattern. The request ID and the remote IP are the ones set by the RequestID
+// and ClientIP middlewares, wherever they are in the chain.
+func AccessLog(opts AccessLogOpts) func(http.Handler) http.Handler {
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+	if opts.RequestIDHeader == "" {
+		opts.RequestIDHeader = "X-Request-Id"
+	}
+	return Named("access_log", func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r, c := withRouteCapture(r)
+			sw := NewResponseWriter(w)
+			start := time.Now()
+			next.ServeHTTP(sw, r)
+			duration := time.Since(start)
+
+			status := sw.Status()
+			if opts.Sample != nil && !opts.Sample(r, status) {
+				return
+			}
+			ctx := r.Context()
+			if !opts.Logger.Enabled(ctx, opts.Level) {
+				return
+			}
+
+			requestID := c.requestID
+			if requestID == "" {
+				requestID = RequestIDFrom(ctx)
+			}
+			if requestID == "" {
+				requestID = r.Header.Get(opts.RequestIDHeader)
+			}
+			ip := c.clientIP
+			if ip == "" {
+				ip = clientIP(r)
+			}
+			values := c.pathValues()
+			pathAttrs := make([]any, 0, len(values))
+			for _, v := range values {
+				pathAttrs = append(pathAttrs, slog.String(v.name, opts.redact("path."+v.name, v.value)))
+			}
+
+			opts.Logger.LogAttrs(ctx, opts.Level, "request",
+				slog.String("method", r.Method),
+				slog.String("pattern", opts.redact("pattern", r.Pattern)),
+				slog.Int("status", status),
+				slog.Int64("bytes", sw.BytesWritten()),
+				slog.Duration("duration", duration),
+				slog.String("remote_ip", opts.redact("remote_ip", ip)),
+				slog.String("request_id", opts.redact("request_id", requestID)),
+				slog.Group("path", pathAttrs...),
+			)
+		})
+	})
+}
+
+// redact returns the value, or the redacted placeholder if the key is listed in Redact.
+func (o AccessLogOpts) redact(key, value string) string {
+	if value != "" && slices.Contains(o.Redact, key) {
+		return redacted
+	}
+	return value
+}
+
+// remoteIP returns the IP of the request's remote address.
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,104 @@
+package routegroup_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-pkgz/routegroup"
+)
+
+func TestAccessLog(t *testing.T) {
+	newRouter := func(opts routegroup.AccessLogOpts) (*routegroup.Bundle, *bytes.Buffer) {
+		buf := &bytes.Buffer{}
+		opts.Logger = slog.New(slog.NewJSONHandler(buf, nil))
+		rtr := routegroup.New(http.NewServeMux())
+		rtr.Use(routegroup.AccessLog(opts))
+		rtr.Use(func(next http.Handler) http.Handler { // root middleware replacing the request
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { next.ServeHTTP(w, r.Clone(r.Context())) })
+		})
+		api := rtr.Mount("/api")
+		api.HandleFunc("GET /users/{id}/tokens/{token}", func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte("hello"))
+		})
+		api.HandleFunc("GET /fail", func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusInternalServerError) })
+		return rtr, buf
+	}
+	records := func(t *testing.T, buf *bytes.Buffer) []map[string]any {
+		t.Helper()
+		var res []map[string]any
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			if line == "" {
+				continue
+			}
+			rec := map[string]any{}
+			if err := json.Unmarshal([]byte(line), &rec); err != nil {
+				t.Fatalf("invalid record %q: %v", line, err)
+			}
+			res = append(res, rec)
+		}
+		return res
+	}
+
+	t.Run("fields", func(t *testing.T) {
+		rtr, buf := newRouter(routegroup.AccessLogOpts{Redact: []string{"path.token"}})
+		req := httptest.NewRequest(http.MethodGet, "/api/users/42/tokens/secret", http.NoBody)
+		req.RemoteAddr = "10.0.0.1:1234"
+		req.Header.Set("X-Request-Id", "req-1")
+		rtr.ServeHTTP(httptest.NewRecorder(), req)
+
+		recs := records(t, buf)
+		if len(recs) != 1 {
+			t.Fatalf("expected 1 record, got %d", len(recs))
+		}
+		rec := recs[0]
+		want := map[string]any{"msg": "request", "method": "GET", "pattern": "GET /api/users/{id}/tokens/{token}",
+			"status": 201.0, "bytes": 5.0, "remote_ip": "10.0.0.1", "request_id": "req-1"}
+		for k, v := range want {
+			if rec[k] != v {
+				t.Errorf("%s: got %v, want %v", k, rec[k], v)
+			}
+		}
+		if _, ok := rec["duration"]; !ok {
+			t.Error("no duration")
+		}
+		path, _ := rec["path"].(map[string]any)
+		if path["id"] != "42" || path["token"] != "[redacted]" {
+			t.Errorf("unexpected path values %v", rec["path"])
+		}
+		if strings.Contains(buf.String(), "secret") {
+			t.Errorf("redacted value logged: %s", buf.String())
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		rtr, buf := newRouter(routegroup.AccessLogOpts{})
+		rtr.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/nope", http.NoBody))
+		recs := records(t, buf)
+		if len(recs) != 1 || recs[0]["status"] != 404.0 || recs[0]["pattern"] != "" {
+			t.Errorf("unexpected records %v", recs)
+		}
+	})
+
+	t.Run("sampling", func(t *testing.T) {
+		rtr, buf := newRouter(routegroup.AccessLogOpts{Sample: func(_ *http.Request, status int) bool { return status >= 500 }})
+		rtr.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/users/1/tokens/2", http.NoBody))
+		rtr.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/fail", http.NoBody))
+		recs := records(t, buf)
+		if len(recs) != 1 || recs[0]["pattern"] != "GET /api/fail" {
+			t.Errorf("unexpected records %v", recs)
+		}
+	})
+
+	t.Run("named", func(t *testing.T) {
+		rtr, _ := newRouter(routegroup.AccessLogOpts{})
+		if mws := rtr.Routes()[0].Middlewares; len(mws) == 0 || mws[0] != "access_log" {
+			t.Errorf("unexpected middlewares %v", mws)
+		}
+	})
+}
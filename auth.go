@@ -0,0 +1,183 @@
+package routegroup
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// AuthDenyHandler renders responses to requests denied by authentication middlewares. It gets the status,
+// 401 for missing or invalid credentials. The WWW-Authenticate header is already set when it's called.
+type AuthDenyHandler func(w http.ResponseWriter, r *http.Request, status int)
+
+// BasicAuthOpts configures BasicAuth. Either Users or Validate must be set.
+type BasicAuthOpts struct {
+	Realm string // realm sent in WWW-Authenticate, "Restricted" by default
+
+	// Users maps user names to passwords, compared in constant time. The principal is the user name.
+	Users map[string]string
+
+	// Validate checks the credentials and returns the principal, used if Users is not set.
+	Validate func(ctx context.Context, user, password string) (principal any, ok bool)
+
+	Deny AuthDenyHandler // renders denials, http.Error with the status text by default
+}
+
+// BearerAuthOpts configures BearerAuth.
+type BearerAuthOpts struct {
+	Realm string // realm sent in WWW-Authenticate, omitted if empty
+
+	// Validate checks the token from the "Authorization: Bearer" header and returns the principal. Required.
+	Validate func(ctx context.Context, token string) (principal any, err error)
+
+	Deny AuthDenyHandler // renders denials, http.Error with the status text by default
+}
+
+// APIKeyAuthOpts configures APIKeyAuth. Either Keys or Validate must be set.
+type APIKeyAuthOpts struct {
+	Header string // header with the key, "X-API-Key" by default
+	Query  string // query parameter with the key, used if the header is missing; not checked if empty
+
+	// Keys maps valid keys to their principals, compared in constant time.
+	Keys map[string]any
+
+	// Validate checks the key and returns the principal, used if Keys is not set.
+	Validate func(ctx context.Context, key string) (principal any, ok bool)
+
+	Deny AuthDenyHandler // renders denials, http.Error with the status text by default
+}
+
+type principalKey struct{}
+
+// Principal returns the principal stored in the context by an authentication middleware. The result is
+// false if there is none or it's not of type T, e.g. Principal[string] returns the user name set by BasicAuth
+// with Users.
+func Principal[T any](ctx context.Context) (T, bool) {
+	p, ok := ctx.Value(principalKey{}).(T)
+	return p, ok
+}
+
+// BasicAuth makes a middleware requiring HTTP Basic authentication. The middleware is named "basic_auth".
+func BasicAuth(opts BasicAuthOpts) func(http.Handler) http.Handler {
+	if opts.Users == nil && opts.Validate == nil {
+		panic("routegroup: basic auth needs Users or Validate")
+	}
+	if opts.Realm == "" {
+		opts.Realm = "Restricted"
+	}
+	validate := opts.Validate
+	if opts.Users != nil {
+		users := make(map[string]any, len(opts.Users))
+		for user, password := range opts.Users {
+			users[user+"\x00"+password] = user
+		}
+		match := secretMatcher(users)
+		validate = func(_ context.Context, user, password string) (any, bool) { return match(user + "\x00" + password) }
+	}
+	challenge := `Basic realm="` + strings.ReplaceAll(opts.Realm, `"`, `\"`) + `", charset="UTF-8"`
+
+	return Named("basic_auth", authMiddleware(opts.Deny, challenge, func(r *http.Request) (any, bool) {
+		user, password, ok := r.BasicAuth()
+		if !ok {
+			return nil, false
+		}
+		return validate(r.Context(), user, password)
+	}))
+}
+
+// BearerAuth makes a middleware requiring a bearer token checked with the validator.
+// The middleware is named "bearer_auth".
+func BearerAuth(opts BearerAuthOpts) func(http.Handler) http.Handler {
+	if opts.Validate == nil {
+		panic("routegroup: bearer auth needs Validate")
+	}
+	challenge := "Bearer"
+	if opts.Realm != "" {
+		challenge += ` realm="` + strings.ReplaceAll(opts.Realm, `"`, `\"`) + `"`
+	}
+
+	return Named("bearer_auth", authMiddleware(opts.Deny, challenge, func(r *http.Request) (any, bool) {
+		scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+			return nil, false
+		}
+		principal, err := opts.Validate(r.Context(), strings.TrimSpace(token))
+		return principal, err == nil
+	}))
+}
+
+// APIKeyAuth makes a middleware requiring an API key in a header or a query parameter.
+// The middleware is named "api_key_auth".
+func APIKeyAuth(opts APIKeyAuthOpts) func(http.Handler) http.Handler {
+	if opts.Keys == nil && opts.Validate == nil {
+		panic("routegroup: api key auth needs Keys or Validate")
+	}
+	if opts.Header == "" {
+		opts.Header = "X-API-Key"
+	}
+	validate := opts.Validate
+	if opts.Keys != nil {
+		match := secretMatcher(opts.Keys)
+		validate = func(_ context.Context, key string) (any, bool) { return match(key) }
+	}
+
+	return Named("api_key_auth", authMiddleware(opts.Deny, "", func(r *http.Request) (any, bool) {
+		key := r.Header.Get(opts.Header)
+		if key == "" && opts.Query != "" {
+			key = r.URL.Query().Get(opts.Query)
+		}
+		if key == "" {
+			return nil, false
+		}
+		return validate(r.Context(), key)
+	}))
+}
+
+// authMiddleware makes a middleware authenticating requests with the check function, storing
+// the principal in the context or denying the request with 401.
+func authMiddleware(deny AuthDenyHandler, challenge string, check func(r *http.Request) (any, bool)) func(http.Handler) http.Handler {
+	if deny == nil {
+		deny = func(w http.ResponseWriter, _ *http.Request, status int) {
+			http.Error(w, http.StatusText(status), status)
+		}
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := check(r)
+			if !ok {
+				if challenge != "" {
+					w.Header().Set("WWW-Authenticate", challenge)
+				}
+				deny(w, r, http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), principalKey{}, principal)))
+		})
+	}
+}
+
+// secretMatcher returns a function looking up the principal of a secret. All secrets are compared
+// in constant time, by their digests, so neither the secret nor its length leak through timing.
+func secretMatcher(secrets map[string]any) func(secret string) (any, bool) {
+	type entry struct {
+		digest    [sha256.Size]byte
+		principal any
+	}
+	entries := make([]entry, 0, len(secrets))
+	for s, p := range secrets {
+		entries = append(entries, entry{digest: sha256.Sum256([]byte(s)), principal: p})
+	}
+	return func(secret string) (any, bool) {
+		digest := sha256.Sum256([]byte(secret))
+		var found any
+		var ok bool
+		for _, e := range entries {
+			if subtle.ConstantTimeCompare(digest[:], e.digest[:]) == 1 {
+				found, ok = e.principal, true
+			}
+		}
+		return found, ok
+	}
+}
@@ -0,0 +1,65 @@
+package routegroup_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/go-pkgz/routegroup"
+)
+
+// discardWriter is a ResponseWriter reusing its header map, so benchmarks count only router allocations.
+type discardWriter struct {
+	header http.Header
+	status int
+}
+
+func (w *discardWriter) Header() http.Header         { return w.header }
+func (w *discardWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w *discardWriter) WriteHeader(status int)      { w.status = status }
+
+func benchRouter() *routegroup.Bundle {
+	mw := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { next.ServeHTTP(w, r) })
+	}
+	ok := func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) }
+
+	rtr := routegroup.New(http.NewServeMux())
+	rtr.Use(mw, mw, mw)
+	rtr.NotFoundHandler(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusNotFound) })
+	rtr.HandleFunc("GET /status", ok)
+	api := rtr.Mount("/api")
+	api.Use(mw)
+	api.HandleFunc("GET /users/{id}", ok)
+	return rtr
+}
+
+func benchServe(b *testing.B, h http.Handler, method, path string, status int) {
+	b.Helper()
+	req, err := http.NewRequest(method, path, http.NoBody)
+	if err != nil {
+		b.Fatal(err)
+	}
+	w := &discardWriter{header: http.Header{}}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		w.status = http.StatusOK
+		h.ServeHTTP(w, req)
+	}
+	b.StopTimer()
+	if w.status != status {
+		b.Fatalf("expected status %d, got %d", status, w.status)
+	}
+}
+
+func BenchmarkServeRoot(b *testing.B) {
+	benchServe(b, benchRouter(), http.MethodGet, "/status", http.StatusOK)
+}
+
+func BenchmarkServeMounted(b *testing.B) {
+	benchServe(b, benchRouter(), http.MethodGet, "/api/users/123", http.StatusOK)
+}
+
+func BenchmarkServeNotFound(b *testing.B) {
+	benchServe(b, benchRouter(), http.MethodGet, "/api/unknown", http.StatusNotFound)
+}
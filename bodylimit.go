@@ -0,0 +1,157 @@
+package routegroup
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+)
+
+// MaxBodySize returns a new Group limiting the size of request bodies of routes registered on it to n bytes.
+// Requests with a larger Content-Length are rejected with 413 before the handler runs. Bodies of unknown
+// length, e.g. chunked uploads, are limited with http.MaxBytesReader, and once the limit is exceeded
+// the response is replaced with 413 as well, whatever the handler writes. 413 responses are rendered with
+// the custom ErrorHandler if set. The innermost limit wins, so a route or a nested group can override
+// the limit of its group, either way; n <= 0 removes the limit. The middleware is named "max_body_size".
+func (b *Bundle) MaxBodySize(n int64) *Bundle {
+	mu := b.treeLock()
+	mu.Lock()
+	defer mu.Unlock()
+	nb := b.clone()
+	nb.middlewares = append(nb.middlewares, &middleware{fn: Named("max_body_size", func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body := r.Body
+			if lb, ok := body.(*limitedBody); ok {
+				body = lb.orig // an outer limit is overridden
+			}
+			if n <= 0 || body == nil || body == http.NoBody {
+				if body != r.Body {
+					r2 := *r
+					r2.Body = body
+					r = &r2
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+			if r.ContentLength > n {
+				w.Header().Set("Connection", "close")
+				nb.renderError(w, r, http.StatusRequestEntityTooLarge)
+				return
+			}
+
+			lb := &limitedBody{ReadCloser: http.MaxBytesReader(w, body, n), orig: body}
+			r2 := *r // shallow copy is enough, only the body changes
+			r2.Body = lb
+			lw := &bodyLimitWriter{ResponseWriter: w, bundle: nb, body: lb, req: &r2}
+			next.ServeHTTP(lw, &r2)
+			if lb.exceeded && !lw.wrote {
+				lw.reject()
+			}
+		})
+	})})
+	return nb
+}
+
+// limitedBody is a request body limited by MaxBodySize, keeping the original body for overrides.
+type limitedBody struct {
+	io.ReadCloser
+	orig     io.ReadCloser
+	exceeded bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	var mbe *http.MaxBytesError
+	if errors.As(err, &mbe) {
+		b.exceeded = true
+	}
+	return n, err
+}
+
+// bodyLimitWriter replaces the handler's response with 413 if the body limit was exceeded
+// before the handler started writing.
+type bodyLimitWriter struct {
+	http.ResponseWriter
+	bundle   *Bundle
+	body     *limitedBody
+	req      *http.Request
+	wrote    bool // handler started writing
+	rejected bool // response replaced with 413, the handler's one is discarded
+}
+
+func (w *bodyLimitWriter) WriteHeader(status int) {
+	if w.start() {
+		w.ResponseWriter.WriteHeader(status)
+	}
+}
+
+func (w *bodyLimitWriter) Write(p []byte) (int, error) {
+	if w.start() {
+		return w.ResponseWriter.Write(p)
+	}
+	return len(p), nil
+}
+
+// start is called on each write, rejecting the request on the first one if the limit was exceeded.
+// It returns whether the handler's write should pass through.
+func (w *bodyLimitWriter) start() bool {
+	if !w.wrote {
+		w.wrote = true
+		if w.body.exceeded {
+			w.reject()
+		}
+	}
+	return !w.rejected
+}
+
+func (w *bodyLimitWriter) reject() {
+	w.rejected = true
+	w.ResponseWriter.Header().Set("Connection", "close")
+	w.bundle.renderError(w.ResponseWriter, w.req, http.StatusRequestEntityTooLarge)
+}
+
+// ReadFrom copies the body from the reader using io.ReaderFrom of the wrapped writer if available.
+// The body is discarded if the response was replaced with 413.
+func (w *bodyLimitWriter) ReadFrom(r io.Reader) (int64, error) {
+	if !w.start() {
+		return io.Copy(io.Discard, r)
+	}
+	if rf, ok := w.ResponseWriter.(io.ReaderFrom); ok {
+		return rf.ReadFrom(r)
+	}
+	return io.Copy(struct{ io.Writer }{w.ResponseWriter}, r)
+}
+
+// Flush sends buffered data to the client, ignoring errors, see FlushError.
+func (w *bodyLimitWriter) Flush() {
+	_ = w.FlushError()
+}
+
+// FlushError sends buffered data to the client. Like a write, it replaces the response with 413
+// if the limit was exceeded, and does nothing once the response is replaced.
+// http.ResponseController uses it for Flush.
+func (w *bodyLimitWriter) FlushError() error {
+	if !w.start() {
+		return nil
+	}
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Hijack lets the caller take over the connection, unless the response was replaced with 413.
+func (w *bodyLimitWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if w.rejected {
+		return nil, nil, errBodyLimitRejected
+	}
+	conn, rw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err == nil {
+		w.wrote = true // the connection is not ours to reject anymore
+	}
+	return conn, rw, err
+}
+
+// errBodyLimitRejected is returned by Hijack if the response was replaced with 413.
+var errBodyLimitRejected = errors.New("routegroup: request body too large, response replaced with 413")
+
+// Unwrap returns the wrapped writer for http.ResponseController.
+func (w *bodyLimitWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
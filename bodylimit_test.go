@@ -0,0 +1,167 @@
+package routegroup_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-pkgz/routegroup"
+)
+
+func TestMaxBodySize(t *testing.T) {
+	// echo reads the whole body and reports read errors with 400, as handlers typically do
+	echo := func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			var mbe *http.MaxBytesError
+			if !errors.As(err, &mbe) {
+				t.Errorf("unexpected error %v", err)
+			}
+			http.Error(w, "bad body", http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write(body)
+	}
+	// ignoreErr reads the body, not writing anything on error
+	ignoreErr := func(_ http.ResponseWriter, r *http.Request) { _, _ = io.ReadAll(r.Body) }
+
+	rtr := routegroup.New(http.NewServeMux())
+	rtr.ErrorHandler(func(w http.ResponseWriter, _ *http.Request, status int) {
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte("custom " + http.StatusText(status)))
+	})
+	api := rtr.Mount("/api").MaxBodySize(10)
+	api.HandleFunc("POST /json", echo)
+	api.HandleFunc("POST /quiet", ignoreErr)
+	api.MaxBodySize(20).HandleFunc("POST /bigger", echo)
+	api.MaxBodySize(0).HandleFunc("POST /unlimited", echo)
+	api.Mount("/upload").MaxBodySize(5).HandleFunc("POST /small", echo)
+	rtr.HandleFunc("POST /outside", echo)
+
+	tbl := []struct {
+		name, path, body string
+		chunked          bool
+		status           int
+		resp             string
+	}{
+		{"within limit", "/api/json", "0123456789", false, 200, "0123456789"},
+		{"content length over limit", "/api/json", "0123456789a", false, 413, "custom Request Entity Too Large"},
+		{"chunked within limit", "/api/json", "0123456789", true, 200, "0123456789"},
+		{"chunked over limit, handler writes", "/api/json", "0123456789a", true, 413, "custom Request Entity Too Large"},
+		{"chunked over limit, handler silent", "/api/quiet", "0123456789a", true, 413, "custom Request Entity Too Large"},
+		{"route override larger", "/api/bigger", strings.Repeat("x", 20), true, 200, strings.Repeat("x", 20)},
+		{"route override larger exceeded", "/api/bigger", strings.Repeat("x", 21), false, 413, "custom Request Entity Too Large"},
+		{"unlimited", "/api/unlimited", strings.Repeat("x", 100), true, 200, strings.Repeat("x", 100)},
+		{"nested group smaller", "/api/upload/small", "123456", true, 413, "custom Request Entity Too Large"},
+		{"outside the group", "/outside", strings.Repeat("x", 100), false, 200, strings.Repeat("x", 100)},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			if tt.chunked {
+				req.ContentLength = -1 // unknown length, as for chunked uploads
+			}
+			rec := httptest.NewRecorder()
+			rtr.ServeHTTP(rec, req)
+			if rec.Code != tt.status || rec.Body.String() != tt.resp {
+				t.Errorf("got %d %q, want %d %q", rec.Code, rec.Body.String(), tt.status, tt.resp)
+			}
+			if tt.status == http.StatusRequestEntityTooLarge && rec.Header().Get("Connection") != "close" {
+				t.Error("connection not closed")
+			}
+		})
+	}
+}
+
+func TestMaxBodySizeWriterInterfaces(t *testing.T) {
+	rtr := routegroup.New(http.NewServeMux())
+	limited := rtr.MaxBodySize(10)
+
+	read := make(chan struct{})
+	limited.HandleFunc("POST /events", func(w http.ResponseWriter, _ *http.Request) {
+		f, ok := w.(http.Flusher)
+		if !ok {
+			t.Error("writer is not a flusher")
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("data: 1\n\n"))
+		f.Flush()
+		<-read // the client gets the event before the handler returns
+		_, _ = w.Write([]byte("data: 2\n\n"))
+	})
+	limited.HandleFunc("POST /hijack", func(w http.ResponseWriter, _ *http.Request) {
+		conn, rw, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			t.Errorf("hijack failed: %v", err)
+			return
+		}
+		defer conn.Close()
+		_, _ = rw.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 8\r\nConnection: close\r\n\r\nhijacked")
+		_ = rw.Flush()
+	})
+	limited.HandleFunc("POST /copy", func(w http.ResponseWriter, _ *http.Request) {
+		if _, ok := w.(io.ReaderFrom); !ok {
+			t.Error("writer is not a reader from")
+		}
+		_, _ = io.Copy(w, strings.NewReader("copied"))
+	})
+	ts := httptest.NewServer(rtr)
+	defer ts.Close()
+
+	t.Run("flush", func(t *testing.T) {
+		resp, err := http.Post(ts.URL+"/events", "text/plain", strings.NewReader("small"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		buf := make([]byte, len("data: 1\n\n"))
+		if _, err = io.ReadFull(resp.Body, buf); err != nil || string(buf) != "data: 1\n\n" {
+			t.Errorf("unexpected first event %q, %v", buf, err)
+		}
+		close(read)
+		rest, _ := io.ReadAll(resp.Body)
+		if string(rest) != "data: 2\n\n" {
+			t.Errorf("unexpected second event %q", rest)
+		}
+	})
+
+	t.Run("hijack", func(t *testing.T) {
+		resp, err := http.Post(ts.URL+"/hijack", "text/plain", strings.NewReader("small"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if body, _ := io.ReadAll(resp.Body); string(body) != "hijacked" {
+			t.Errorf("unexpected body %q", body)
+		}
+	})
+
+	t.Run("read from", func(t *testing.T) {
+		resp, err := http.Post(ts.URL+"/copy", "text/plain", strings.NewReader("small"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if body, _ := io.ReadAll(resp.Body); resp.StatusCode != http.StatusOK || string(body) != "copied" {
+			t.Errorf("unexpected response %d %q", resp.StatusCode, body)
+		}
+	})
+
+	t.Run("flush after exceeded limit", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		h := rtr.MaxBodySize(2)
+		h.HandleFunc("POST /stream", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.ReadAll(r.Body)
+			w.(http.Flusher).Flush()
+			_, _ = w.Write([]byte("data"))
+		})
+		rtr.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/stream", io.MultiReader(strings.NewReader("too large"))))
+		if rec.Code != http.StatusRequestEntityTooLarge || strings.Contains(rec.Body.String(), "data") {
+			t.Errorf("unexpected response %d %q", rec.Code, rec.Body.String())
+		}
+	})
+}
@@ -0,0 +1,70 @@
+package routegroup
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// routeCapture collects details of the matched route for root middlewares. Root middlewares run before
+// the mux matches the route, so path values are not set on their request; a middleware needing them
+// puts a capture in the request context and the matched route fills it before calling its handler.
+// Inner middlewares record details for the outer ones the same way, e.g. the request ID.
+type routeCapture struct {
+	route     *route
+	request   *http.Request // request as seen by the route, with path values set
+	requestID string        // set by RequestID
+	clientIP  string        // set by ClientIP
+}
+
+type routeCaptureKey struct{}
+
+// withRouteCapture returns the request with a new capture in its context.
+func withRouteCapture(r *http.Request) (*http.Request, *routeCapture) {
+	c := &routeCapture{}
+	return r.WithContext(context.WithValue(r.Context(), routeCaptureKey{}, c)), c
+}
+
+// capture fills the capture of the request context, if any.
+func (rt *route) capture(r *http.Request) {
+	if c, ok := r.Context().Value(routeCaptureKey{}).(*routeCapture); ok {
+		c.route, c.request = rt, r
+	}
+}
+
+// pathValues returns the path values of the matched route in pattern order, nil if no route matched.
+func (c *routeCapture) pathValues() []pathValue {
+	if c.route == nil || len(c.route.wildcards) == 0 {
+		return nil
+	}
+	res := make([]pathValue, 0, len(c.route.wildcards))
+	for _, name := range c.route.wildcards {
+		res = append(res, pathValue{name: name, value: c.request.PathValue(name)})
+	}
+	return res
+}
+
+type pathValue struct {
+	name, value string
+}
+
+// patternWildcards returns names of the wildcards in the pattern, e.g. "id" and "path"
+// for "GET /users/{id}/files/{path...}".
+func patternWildcards(pattern string) []string {
+	var res []string
+	for {
+		start := strings.IndexByte(pattern, '{')
+		if start < 0 {
+			return res
+		}
+		end := strings.IndexByte(pattern[start:], '}')
+		if end < 0 {
+			return res
+		}
+		name := strings.TrimSuffix(pattern[start+1:start+end], "...")
+		if name != "$" {
+			res = append(res, name)
+		}
+		pattern = pattern[start+end+1:]
+	}
+}
@@ -0,0 +1,180 @@
+package routegroup
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ClientIPOpts configures ClientIP.
+type ClientIPOpts struct {
+	// TrustedProxies lists IPs and CIDR ranges of proxies trusted to report the client IP
+	// in the Header, e.g. "10.0.0.0/8".
+	TrustedProxies []string
+
+	// Header is the request header the trusted proxies set with the client address, "X-Forwarded-For"
+	// if empty. "Forwarded" (RFC 7239) is parsed for its "for" parameters, other headers as comma-separated
+	// addresses, e.g. "X-Real-IP". Only this header is read, as proxies pass other ones from the client through.
+	Header string
+}
+
+// IPFilterOpts configures IPFilter with IPs and CIDR ranges, e.g. "10.8.0.0/16" or "192.0.2.1".
+type IPFilterOpts struct {
+	Allow []string // if set, only clients in these ranges are allowed
+	Deny  []string // clients in these ranges are denied, even if allowed by Allow
+}
+
+type clientIPKey struct{}
+
+// ClientIP makes a middleware resolving the client IP and storing it in the request context, see
+// ClientIPFrom. The header set by the proxies, X-Forwarded-For by default, is honored only for requests from
+// trusted proxies: the client is the rightmost address in the header not belonging to a trusted proxy,
+// as addresses to its left can be forged by the client. Without trusted proxies the client IP is the remote address of the connection. AccessLog, RateLimitByIP
+// and IPFilter use the resolved IP, so it is best used as the first root middleware.
+// The middleware is named "client_ip".
+func ClientIP(opts ClientIPOpts) func(http.Handler) http.Handler {
+	trusted := parsePrefixes(opts.TrustedProxies)
+	header := http.CanonicalHeaderKey(opts.Header)
+	if header == "" {
+		header = "X-Forwarded-For"
+	}
+	return Named("client_ip", func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := resolveClientIP(r, trusted, header)
+			if c, ok := r.Context().Value(routeCaptureKey{}).(*routeCapture); ok {
+				c.clientIP = ip // for the access log running before this middleware
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientIPKey{}, ip)))
+		})
+	})
+}
+
+// ClientIPFrom returns the client IP resolved by the ClientIP middleware, empty if there is none.
+func ClientIPFrom(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey{}).(string)
+	return ip
+}
+
+// clientIP returns the client IP resolved by ClientIP, or the remote IP of the request.
+func clientIP(r *http.Request) string {
+	if ip := ClientIPFrom(r.Context()); ip != "" {
+		return ip
+	}
+	return remoteIP(r)
+}
+
+// IPFilter returns a new Group allowing or denying requests of routes registered on it by the client IP,
+// as resolved by the ClientIP middleware, or the remote address without it. Denied requests are rejected
+// with 403, rendered with the custom ErrorHandler if set. The middleware is named "ip_filter".
+func (b *Bundle) IPFilter(opts IPFilterOpts) *Bundle {
+	allow, deny := parsePrefixes(opts.Allow), parsePrefixes(opts.Deny)
+
+	mu := b.treeLock()
+	mu.Lock()
+	defer mu.Unlock()
+	nb := b.clone()
+	nb.middlewares = append(nb.middlewares, &middleware{fn: Named("ip_filter", func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip, err := netip.ParseAddr(clientIP(r))
+			if err != nil || containsIP(deny, ip.Unmap()) || (len(allow) > 0 && !containsIP(allow, ip.Unmap())) {
+				nb.renderError(w, r, http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	})})
+	return nb
+}
+
+// resolveClientIP returns the client IP of the request reported in the header by trusted proxies, see ClientIP.
+func resolveClientIP(r *http.Request, trusted []netip.Prefix, header string) string {
+	remote, err := netip.ParseAddr(remoteIP(r))
+	if err != nil {
+		return remoteIP(r)
+	}
+	ip := remote.Unmap()
+	if !containsIP(trusted, ip) {
+		return ip.String()
+	}
+
+	var hops []string
+	if header == "Forwarded" {
+		hops = forwardedFor(r.Header.Values(header))
+	} else {
+		for _, v := range r.Header.Values(header) {
+			hops = append(hops, strings.Split(v, ",")...)
+		}
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, ok := parseForwardedIP(hops[i])
+		if !ok {
+			break // the previous, trusted, hop is the best known client address
+		}
+		ip = hop
+		if !containsIP(trusted, ip) {
+			break
+		}
+	}
+	return ip.String()
+}
+
+// forwardedFor returns "for" parameters of Forwarded headers.
+func forwardedFor(values []string) []string {
+	var res []string
+	for _, v := range values {
+		for _, elem := range strings.Split(v, ",") {
+			for _, pair := range strings.Split(elem, ";") {
+				if k, val, ok := strings.Cut(strings.TrimSpace(pair), "="); ok && strings.EqualFold(k, "for") {
+					res = append(res, val)
+				}
+			}
+		}
+	}
+	return res
+}
+
+// parseForwardedIP parses an address from a forwarding header, with optional quotes, brackets and port.
+func parseForwardedIP(s string) (netip.Addr, bool) {
+	s = strings.Trim(strings.TrimSpace(s), `"`)
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+	ip, err := netip.ParseAddr(strings.Trim(s, "[]"))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return ip.Unmap(), true
+}
+
+// parsePrefixes parses IPs and CIDR ranges, panicking on invalid ones.
+func parsePrefixes(list []string) []netip.Prefix {
+	res := make([]netip.Prefix, 0, len(list))
+	for _, s := range list {
+		if !strings.Contains(s, "/") {
+			ip, err := netip.ParseAddr(s)
+			if err != nil {
+				panic("routegroup: invalid IP " + s)
+			}
+			ip = ip.Unmap()
+			res = append(res, netip.PrefixFrom(ip, ip.BitLen()))
+			continue
+		}
+		p, err := netip.ParsePrefix(s)
+		if err != nil {
+			panic("routegroup: invalid CIDR " + s)
+		}
+		res = append(res, p.Masked())
+	}
+	return res
+}
+
+func containsIP(prefixes []netip.Prefix, ip netip.Addr) bool {
+	for _, p := range prefixes {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,145 @@
+package routegroup_test
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-pkgz/routegroup"
+)
+
+func TestClientIP(t *testing.T) {
+	var got string
+	rtr := routegroup.New(http.NewServeMux())
+	rtr.Use(routegroup.ClientIP(routegroup.ClientIPOpts{TrustedProxies: []string{"10.0.0.0/8", "2001:db8::1"}}))
+	rtr.HandleFunc("GET /", func(_ http.ResponseWriter, r *http.Request) { got = routegroup.ClientIPFrom(r.Context()) })
+
+	tbl := []struct {
+		name, remote string
+		headers      map[string][]string
+		want         string
+	}{
+		{"direct", "203.0.113.5:1234", nil, "203.0.113.5"},
+		{"untrusted remote ignores headers", "203.0.113.5:1234", map[string][]string{"X-Forwarded-For": {"1.2.3.4"}}, "203.0.113.5"},
+		{"trusted proxy", "10.0.0.1:1234", map[string][]string{"X-Forwarded-For": {"198.51.100.7"}}, "198.51.100.7"},
+		{"forged left entries", "10.0.0.1:1234", map[string][]string{"X-Forwarded-For": {"1.1.1.1, 198.51.100.7, 10.0.0.2"}}, "198.51.100.7"},
+		{"multiple headers", "10.0.0.1:1234", map[string][]string{"X-Forwarded-For": {"1.1.1.1", "198.51.100.7"}}, "198.51.100.7"},
+		{"all trusted", "10.0.0.1:1234", map[string][]string{"X-Forwarded-For": {"10.0.0.3, 10.0.0.2"}}, "10.0.0.3"},
+		{"invalid entry", "10.0.0.1:1234", map[string][]string{"X-Forwarded-For": {"198.51.100.7, garbage, 10.0.0.2"}}, "10.0.0.2"},
+		{"no header", "10.0.0.1:1234", nil, "10.0.0.1"},
+		{"forged forwarded ignored", "10.0.0.1:1234", map[string][]string{
+			"Forwarded":       {"for=10.8.0.5"},
+			"X-Forwarded-For": {"203.0.113.7"},
+		}, "203.0.113.7"},
+		{"forwarded only ignored", "10.0.0.1:1234", map[string][]string{"Forwarded": {"for=198.51.100.9"}}, "10.0.0.1"},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			req.RemoteAddr = tt.remote
+			for k, v := range tt.headers {
+				req.Header[k] = v
+			}
+			got = ""
+			rtr.ServeHTTP(httptest.NewRecorder(), req)
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	fwd := routegroup.New(http.NewServeMux())
+	fwd.Use(routegroup.ClientIP(routegroup.ClientIPOpts{TrustedProxies: []string{"10.0.0.0/8", "2001:db8::1"}, Header: "forwarded"}))
+	fwd.HandleFunc("GET /", func(_ http.ResponseWriter, r *http.Request) { got = routegroup.ClientIPFrom(r.Context()) })
+	for _, tt := range []struct {
+		name, remote string
+		headers      map[string][]string
+		want         string
+	}{
+		{"forwarded", "10.0.0.1:1234", map[string][]string{
+			"Forwarded":       {`for=198.51.100.9;proto=https, for="10.0.0.5:8080"`},
+			"X-Forwarded-For": {"1.1.1.1"},
+		}, "198.51.100.9"},
+		{"forwarded ipv6", "[2001:db8::1]:443", map[string][]string{"Forwarded": {`For="[2001:db8:cafe::17]:4711"`}}, "2001:db8:cafe::17"},
+		{"forwarded unknown", "10.0.0.1:1234", map[string][]string{"Forwarded": {"for=unknown"}}, "10.0.0.1"},
+		{"forged xff ignored", "10.0.0.1:1234", map[string][]string{"X-Forwarded-For": {"10.8.0.5"}}, "10.0.0.1"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			req.RemoteAddr = tt.remote
+			for k, v := range tt.headers {
+				req.Header[k] = v
+			}
+			got = ""
+			fwd.ServeHTTP(httptest.NewRecorder(), req)
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("invalid proxy", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected panic")
+			}
+		}()
+		routegroup.ClientIP(routegroup.ClientIPOpts{TrustedProxies: []string{"10.0.0.0/99"}})
+	})
+}
+
+func TestIPFilter(t *testing.T) {
+	ok := func(w http.ResponseWriter, _ *http.Request) { _, _ = w.Write([]byte("ok")) }
+	buf := &bytes.Buffer{}
+	rtr := routegroup.New(http.NewServeMux())
+	rtr.Use(routegroup.AccessLog(routegroup.AccessLogOpts{Logger: slog.New(slog.NewTextHandler(buf, nil))}))
+	rtr.Use(routegroup.ClientIP(routegroup.ClientIPOpts{TrustedProxies: []string{"127.0.0.1"}}))
+	rtr.Mount("/admin").IPFilter(routegroup.IPFilterOpts{Allow: []string{"10.8.0.0/16"}, Deny: []string{"10.8.1.0/24"}}).
+		HandleFunc("GET /", ok)
+	rtr.Mount("/public").IPFilter(routegroup.IPFilterOpts{Deny: []string{"192.0.2.1"}}).HandleFunc("GET /", ok)
+
+	tbl := []struct {
+		path, remote, xff string
+		status            int
+	}{
+		{"/admin/", "10.8.3.4:1", "", 200},
+		{"/admin/", "10.8.1.4:1", "", 403},
+		{"/admin/", "203.0.113.1:1", "", 403},
+		{"/admin/", "127.0.0.1:1", "10.8.3.4", 200},   // via trusted proxy
+		{"/admin/", "203.0.113.1:1", "10.8.3.4", 403}, // forged header
+		{"/admin/", "[::ffff:10.8.3.4]:1", "", 200},   // ipv4-mapped
+		{"/public/", "192.0.2.1:1", "", 403},
+		{"/public/", "192.0.2.2:1", "", 200},
+	}
+	for _, tt := range tbl {
+		req := httptest.NewRequest(http.MethodGet, tt.path, http.NoBody)
+		req.RemoteAddr = tt.remote
+		if tt.xff != "" {
+			req.Header.Set("X-Forwarded-For", tt.xff)
+		}
+		rec := httptest.NewRecorder()
+		rtr.ServeHTTP(rec, req)
+		if rec.Code != tt.status {
+			t.Errorf("%s from %s (%s): got %d, want %d", tt.path, tt.remote, tt.xff, rec.Code, tt.status)
+		}
+	}
+
+	t.Run("forged forwarded with real xff", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/admin/", http.NoBody)
+		req.RemoteAddr = "127.0.0.1:1"
+		req.Header.Set("X-Forwarded-For", "203.0.113.7")
+		req.Header.Set("Forwarded", "for=10.8.0.5")
+		rec := httptest.NewRecorder()
+		rtr.ServeHTTP(rec, req)
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("expected 403, got %d", rec.Code)
+		}
+	})
+
+	if !strings.Contains(buf.String(), "remote_ip=10.8.3.4") || strings.Contains(buf.String(), "remote_ip=127.0.0.1") {
+		t.Errorf("access log doesn't use the resolved client ip:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,132 @@
+package routegroup_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/go-pkgz/routegroup"
+)
+
+// these tests are meant to run with the race detector, i.e. go test -race
+
+func TestConcurrentRegistrationAndServing(t *testing.T) {
+	rtr := routegroup.New(http.NewServeMux())
+	rtr.Use(routegroup.Named("log", passThrough), routegroup.Named("auth", passThrough))
+	api := rtr.Mount("/api")
+	api.HandleFunc("GET /static", func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) })
+
+	const plugins, routesPerPlugin = 8, 20
+	var stop atomic.Bool
+	var serveWg, registerWg sync.WaitGroup
+
+	// serve requests continuously, for both existing and not yet registered routes
+	for i := 0; i < 4; i++ {
+		serveWg.Add(1)
+		go func(i int) {
+			defer serveWg.Done()
+			for n := 0; !stop.Load(); n++ {
+				rec := httptest.NewRecorder()
+				rtr.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/static", http.NoBody))
+				if rec.Code != http.StatusOK {
+					t.Errorf("static route: expected 200, got %d", rec.Code)
+					return
+				}
+				rec = httptest.NewRecorder()
+				path := fmt.Sprintf("/api/plugin%d/route%d", n%plugins, n%routesPerPlugin)
+				rtr.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, http.NoBody))
+				// a request racing with registration of its route may see 404, or 405 as the mux
+				// looks up the route and the allowed methods separately
+				if rec.Code != http.StatusOK && rec.Code != http.StatusNotFound && rec.Code != http.StatusMethodNotAllowed {
+					t.Errorf("%s: unexpected status %d", path, rec.Code)
+					return
+				}
+				_ = rtr.Routes()
+			}
+		}(i)
+	}
+
+	// load plugins concurrently, each registering its own group with routes
+	for p := 0; p < plugins; p++ {
+		registerWg.Add(1)
+		go func(p int) {
+			defer registerWg.Done()
+			grp := api.Mount(fmt.Sprintf("/plugin%d", p))
+			grp.Use(passThrough)
+			if p%2 == 0 {
+				grp = grp.Without("auth")
+			}
+			for r := 0; r < routesPerPlugin; r++ {
+				grp.HandleFunc(fmt.Sprintf("GET /route%d", r), func(w http.ResponseWriter, _ *http.Request) {
+					w.WriteHeader(http.StatusOK)
+				})
+			}
+		}(p)
+	}
+
+	// change the custom 404 handler concurrently
+	registerWg.Add(1)
+	go func() {
+		defer registerWg.Done()
+		for i := 0; i < 50; i++ {
+			rtr.NotFoundHandler(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusNotFound) })
+		}
+	}()
+
+	registerWg.Wait()
+	stop.Store(true)
+	serveWg.Wait()
+
+	if n := len(rtr.Routes()); n != plugins*routesPerPlugin+1 {
+		t.Errorf("expected %d routes, got %d", plugins*routesPerPlugin+1, n)
+	}
+	for p := 0; p < plugins; p++ {
+		rec := httptest.NewRecorder()
+		rtr.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/plugin%d/route3", p), http.NoBody))
+		if rec.Code != http.StatusOK {
+			t.Errorf("plugin %d: expected 200 after registration, got %d", p, rec.Code)
+		}
+	}
+}
+
+func TestConcurrentRootMiddlewareChanges(t *testing.T) {
+	rtr := routegroup.New(http.NewServeMux())
+	child := rtr.Group()
+	child.HandleFunc("/a", func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) })
+
+	var added atomic.Int32
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 20; i++ {
+			rtr.Use(func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.Header().Add("X-Mw", "1")
+					next.ServeHTTP(w, r)
+				})
+			})
+			added.Add(1)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			rec := httptest.NewRecorder()
+			rtr.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/a", http.NoBody))
+			if rec.Code != http.StatusOK {
+				t.Errorf("expected 200, got %d", rec.Code)
+			}
+		}
+	}()
+	wg.Wait()
+
+	rec := httptest.NewRecorder()
+	rtr.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/a", http.NoBody))
+	if n := len(rec.Header().Values("X-Mw")); n != int(added.Load()) {
+		t.Errorf("expected %d middleware headers, got %d", added.Load(), n)
+	}
+}
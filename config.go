@@ -0,0 +1,541 @@
+package routegroup
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Registry holds named handlers, middlewares and file systems route configuration refers to, see LoadConfig.
+type Registry struct {
+	Handlers    map[string]http.Handler
+	Middlewares map[string]func(http.Handler) http.Handler
+	FileSystems map[string]http.FileSystem
+}
+
+// ConfigError reports a problem in route configuration with its position.
+type ConfigError struct {
+	File   string // name of the configuration file, empty if read with LoadConfig
+	Line   int    // 1-based line of the problem
+	Column int    // 1-based column of the problem, in bytes
+	Msg    string
+}
+
+// Error returns the error in "file:line:column: message" form.
+func (e *ConfigError) Error() string {
+	file := e.File
+	if file == "" {
+		file = "config"
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", file, e.Line, e.Column, e.Msg)
+}
+
+// LoadConfigFile is like LoadConfig, reading the configuration from the file.
+func (b *Bundle) LoadConfigFile(path string, reg Registry) error {
+	data, err := os.ReadFile(path) //nolint:gosec // path is provided by the caller on purpose
+	if err != nil {
+		return fmt.Errorf("routegroup: can't read config: %w", err)
+	}
+	return b.loadConfig(data, path, reg)
+}
+
+// LoadConfig configures the bundle from JSON route configuration, resolving handler, middleware
+// and file system names with the registry. The configuration describes a tree of groups using
+// the regular Bundle methods, so the result is the same as if the tree was built in code:
+//
+//	{
+//	  "middlewares": ["log"],                      // Use on the bundle
+//	  "not_found": "notFound",                     // NotFoundHandler
+//	  "require": [{"prefix": "/api", "except": ["/api/health"], "middlewares": ["auth"]}],
+//	  "routes": [{"pattern": "GET /health", "handler": "health"}],
+//	  "groups": [{
+//	    "mount": "/api",                           // Mount, or Group if empty
+//	    "without": ["log"],                        // Without
+//	    "middlewares": ["auth"],                   // Use on the group
+//	    "root": {"method": "GET", "handler": "apiIndex"},  // HandleRoot
+//	    "files": [{"pattern": "/static/", "dir": "./assets"}, {"pattern": "/docs/", "fs": "docs"}],
+//	    "routes": [{"pattern": "POST /users", "handler": "createUser", "middlewares": ["csrf"], "without": ["auth"]}],
+//	    "groups": []
+//	  }]
+//	}
+//
+// Route-level "middlewares" and "without" apply With and Without for that route only.
+// Middlewares from the registry are registered as Named with their registry names, so they can be
+// excluded with "without", required by policies and show up in Routes. Names under "without" must be
+// registry middlewares or named middlewares the bundle already has, e.g. added with Use in code.
+// Errors are returned as *ConfigError with the position of the offending element. Names are checked
+// before the bundle is changed, but a failed registration, e.g. a conflicting pattern, leaves the bundle
+// partially configured, so it's best to load the configuration into a fresh bundle, e.g. one swapped in
+// with Switcher.
+//
+// Only JSON is supported. The standard library has no YAML parser and the package has no dependencies,
+// so YAML configuration has to be converted to JSON by the caller before loading.
+func (b *Bundle) LoadConfig(r io.Reader, reg Registry) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("routegroup: can't read config: %w", err)
+	}
+	return b.loadConfig(data, "", reg)
+}
+
+func (b *Bundle) loadConfig(data []byte, file string, reg Registry) error {
+	l := &configLoader{data: data, file: file, reg: reg, named: b.middlewareNames()}
+	node, err := parseJSONNode(data)
+	if err != nil {
+		var serr *json.SyntaxError
+		if errors.As(err, &serr) {
+			off := serr.Offset
+			if off > 0 && !strings.HasSuffix(serr.Error(), "end of JSON input") {
+				off-- // offset is after the offending byte
+			}
+			return l.errorf(off, "%s", serr.Error())
+		}
+		return l.errorf(int64(len(data)), "%s", err.Error())
+	}
+
+	cfg, err := l.group(node, true)
+	if err != nil {
+		return err
+	}
+	return l.apply(b, cfg, true)
+}
+
+// configLoader decodes route configuration from parsed JSON, keeping positions for errors.
+type configLoader struct {
+	data  []byte
+	file  string
+	reg   Registry
+	named map[string]bool // names of the middlewares the bundle has before loading
+}
+
+// configGroup is a group of the configuration tree. The top-level group is the configured bundle itself.
+type configGroup struct {
+	off         int64
+	mount       string
+	middlewares []configName
+	without     []string
+	notFound    *configName
+	require     []Policy
+	routes      []configRoute
+	files       []configFiles
+	root        *configRoute
+	groups      []*configGroup
+}
+
+type configRoute struct {
+	off         int64
+	pattern     string // pattern for routes, method for the group root
+	handler     http.Handler
+	middlewares []configName
+	without     []string
+}
+
+type configFiles struct {
+	off     int64
+	pattern string
+	fs      http.FileSystem
+}
+
+// configName is a resolved registry reference.
+type configName struct {
+	name string
+	mw   func(http.Handler) http.Handler
+	h    http.Handler
+}
+
+func (l *configLoader) group(n *jsonNode, top bool) (*configGroup, error) {
+	g := &configGroup{off: n.off}
+	fields := map[string]func(*jsonNode) error{
+		"middlewares": func(v *jsonNode) (err error) { g.middlewares, err = l.middlewares(v); return err },
+		"routes": func(v *jsonNode) error {
+			return l.each(v, func(item *jsonNode) error {
+				rt, err := l.route(item, "pattern")
+				g.routes = append(g.routes, rt)
+				return err
+			})
+		},
+		"files": func(v *jsonNode) error {
+			return l.each(v, func(item *jsonNode) error {
+				f, err := l.files(item)
+				g.files = append(g.files, f)
+				return err
+			})
+		},
+		"root": func(v *jsonNode) error {
+			rt, err := l.route(v, "method")
+			g.root = &rt
+			return err
+		},
+		"groups": func(v *jsonNode) error {
+			return l.each(v, func(item *jsonNode) error {
+				sub, err := l.group(item, false)
+				g.groups = append(g.groups, sub)
+				return err
+			})
+		},
+	}
+	if top {
+		fields["not_found"] = func(v *jsonNode) error {
+			h, err := l.handler(v)
+			g.notFound = &h
+			return err
+		}
+		fields["require"] = func(v *jsonNode) error {
+			return l.each(v, func(item *jsonNode) error {
+				p, err := l.policy(item)
+				g.require = append(g.require, p)
+				return err
+			})
+		}
+	} else {
+		fields["mount"] = func(v *jsonNode) (err error) { g.mount, err = l.str(v); return err }
+		fields["without"] = func(v *jsonNode) (err error) { g.without, err = l.without(v); return err }
+	}
+	if err := l.object(n, fields); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// route decodes a route, or the group root if key is "method".
+func (l *configLoader) route(n *jsonNode, key string) (configRoute, error) {
+	rt := configRoute{off: n.off}
+	var hasHandler bool
+	err := l.object(n, map[string]func(*jsonNode) error{
+		key: func(v *jsonNode) (err error) { rt.pattern, err = l.str(v); return err },
+		"handler": func(v *jsonNode) error {
+			h, err := l.handler(v)
+			rt.handler, hasHandler = h.h, true
+			return err
+		},
+		"middlewares": func(v *jsonNode) (err error) { rt.middlewares, err = l.middlewares(v); return err },
+		"without":     func(v *jsonNode) (err error) { rt.without, err = l.without(v); return err },
+	})
+	if err != nil {
+		return rt, err
+	}
+	if key == "pattern" && rt.pattern == "" {
+		return rt, l.errorf(n.off, "route has no pattern")
+	}
+	if !hasHandler {
+		return rt, l.errorf(n.off, "route has no handler")
+	}
+	return rt, nil
+}
+
+func (l *configLoader) files(n *jsonNode) (configFiles, error) {
+	f := configFiles{off: n.off}
+	err := l.object(n, map[string]func(*jsonNode) error{
+		"pattern": func(v *jsonNode) (err error) { f.pattern, err = l.str(v); return err },
+		"dir": func(v *jsonNode) error {
+			dir, err := l.str(v)
+			f.fs = http.Dir(dir)
+			return err
+		},
+		"fs": func(v *jsonNode) error {
+			name, err := l.str(v)
+			if err != nil {
+				return err
+			}
+			fs, ok := l.reg.FileSystems[name]
+			if !ok {
+				return l.errorf(v.off, "unknown file system %q", name)
+			}
+			f.fs = fs
+			return nil
+		},
+	})
+	if err != nil {
+		return f, err
+	}
+	if f.pattern == "" || f.fs == nil {
+		return f, l.errorf(n.off, `files need a pattern and either "dir" or "fs"`)
+	}
+	return f, nil
+}
+
+func (l *configLoader) policy(n *jsonNode) (Policy, error) {
+	var p Policy
+	err := l.object(n, map[string]func(*jsonNode) error{
+		"prefix":      func(v *jsonNode) (err error) { p.Prefix, err = l.str(v); return err },
+		"except":      func(v *jsonNode) (err error) { p.Except, err = l.strs(v); return err },
+		"middlewares": func(v *jsonNode) (err error) { p.Middlewares, err = l.strs(v); return err },
+	})
+	return p, err
+}
+
+func (l *configLoader) middlewares(n *jsonNode) ([]configName, error) {
+	var res []configName
+	err := l.each(n, func(item *jsonNode) error {
+		name, err := l.str(item)
+		if err != nil {
+			return err
+		}
+		mw, ok := l.reg.Middlewares[name]
+		if !ok {
+			return l.errorf(item.off, "unknown middleware %q", name)
+		}
+		res = append(res, configName{name: name, mw: Named(name, mw)})
+		return nil
+	})
+	return res, err
+}
+
+// without decodes names of middlewares to exclude, checking they are known.
+func (l *configLoader) without(n *jsonNode) ([]string, error) {
+	var res []string
+	err := l.each(n, func(item *jsonNode) error {
+		name, err := l.str(item)
+		if err != nil {
+			return err
+		}
+		if _, ok := l.reg.Middlewares[name]; !ok && !l.named[name] {
+			return l.errorf(item.off, "unknown middleware %q", name)
+		}
+		res = append(res, name)
+		return nil
+	})
+	return res, err
+}
+
+func (l *configLoader) handler(n *jsonNode) (configName, error) {
+	name, err := l.str(n)
+	if err != nil {
+		return configName{}, err
+	}
+	h, ok := l.reg.Handlers[name]
+	if !ok {
+		return configName{}, l.errorf(n.off, "unknown handler %q", name)
+	}
+	return configName{name: name, h: h}, nil
+}
+
+// object checks the node is an object with known fields only and decodes each field.
+func (l *configLoader) object(n *jsonNode, fields map[string]func(*jsonNode) error) error {
+	if n.kind != jsonObject {
+		return l.errorf(n.off, "expected object")
+	}
+	seen := make(map[string]bool, len(n.fields))
+	for _, f := range n.fields {
+		decode, ok := fields[f.key]
+		if !ok {
+			return l.errorf(f.off, "unknown field %q", f.key)
+		}
+		if seen[f.key] {
+			return l.errorf(f.off, "duplicate field %q", f.key)
+		}
+		seen[f.key] = true
+		if err := decode(f.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *configLoader) each(n *jsonNode, fn func(*jsonNode) error) error {
+	if n.kind != jsonArray {
+		return l.errorf(n.off, "expected array")
+	}
+	for _, item := range n.items {
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *configLoader) str(n *jsonNode) (string, error) {
+	s, ok := n.scalar.(string)
+	if n.kind != jsonScalar || !ok {
+		return "", l.errorf(n.off, "expected string")
+	}
+	return s, nil
+}
+
+func (l *configLoader) strs(n *jsonNode) ([]string, error) {
+	var res []string
+	err := l.each(n, func(item *jsonNode) error {
+		s, err := l.str(item)
+		res = append(res, s)
+		return err
+	})
+	return res, err
+}
+
+// errorf makes ConfigError with the line and column of the offset.
+func (l *configLoader) errorf(off int64, format string, args ...any) error {
+	if off > int64(len(l.data)) {
+		off = int64(len(l.data))
+	}
+	before := l.data[:off]
+	line := bytes.Count(before, []byte("\n")) + 1
+	col := int(off) - bytes.LastIndexByte(before, '\n')
+	return &ConfigError{File: l.file, Line: line, Column: col, Msg: fmt.Sprintf(format, args...)}
+}
+
+// apply builds the configured tree on the bundle. Registration panics, e.g. for conflicting
+// patterns, are reported as errors with the position of the element.
+func (l *configLoader) apply(b *Bundle, g *configGroup, top bool) (err error) {
+	off := g.off
+	defer func() {
+		if r := recover(); r != nil {
+			err = l.errorf(off, "%v", r)
+		}
+	}()
+
+	if !top {
+		if g.mount != "" {
+			b = b.Mount(g.mount)
+		} else {
+			b = b.Group()
+		}
+		if len(g.without) > 0 {
+			b = b.Without(g.without[0], g.without[1:]...)
+		}
+	}
+	for _, mw := range g.middlewares {
+		b.Use(mw.mw)
+	}
+	if g.notFound != nil {
+		b.NotFoundHandler(g.notFound.h.ServeHTTP)
+	}
+	for _, p := range g.require {
+		b.Require(p)
+	}
+	for _, rt := range g.routes {
+		off = rt.off
+		routeBundle(b, rt).Handle(rt.pattern, rt.handler)
+	}
+	if g.root != nil {
+		off = g.root.off
+		routeBundle(b, *g.root).HandleRoot(g.root.pattern, g.root.handler)
+	}
+	for _, f := range g.files {
+		off = f.off
+		b.HandleFiles(f.pattern, f.fs)
+	}
+	for _, sub := range g.groups {
+		if subErr := l.apply(b, sub, false); subErr != nil {
+			return subErr
+		}
+	}
+	return nil
+}
+
+// middlewareNames returns the names of the bundle's named middlewares, including root ones.
+func (b *Bundle) middlewareNames() map[string]bool {
+	mu := b.treeLock()
+	mu.Lock()
+	defer mu.Unlock()
+	res := make(map[string]bool)
+	for _, stack := range [][]*middleware{b.rootBundle().middlewares, b.middlewares} {
+		for _, mw := range stack {
+			if name := mw.name(); name != "" {
+				res[name] = true
+			}
+		}
+	}
+	return res
+}
+
+// routeBundle applies route-level without and middlewares.
+func routeBundle(b *Bundle, rt configRoute) *Bundle {
+	if len(rt.without) > 0 {
+		b = b.Without(rt.without[0], rt.without[1:]...)
+	}
+	for _, mw := range rt.middlewares {
+		b = b.With(mw.mw)
+	}
+	return b
+}
+
+type jsonKind int
+
+const (
+	jsonScalar jsonKind = iota
+	jsonObject
+	jsonArray
+)
+
+// jsonNode is a parsed JSON value with the offset of its start, used to report errors by position.
+type jsonNode struct {
+	off    int64
+	kind   jsonKind
+	scalar any // string, float64, bool or nil
+	fields []jsonField
+	items  []*jsonNode
+}
+
+type jsonField struct {
+	key   string
+	off   int64 // offset of the key
+	value *jsonNode
+}
+
+// parseJSONNode parses a single JSON value keeping offsets of all values and object keys.
+func parseJSONNode(data []byte) (*jsonNode, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	n, err := parseJSONValue(dec, data)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = dec.Token(); !errors.Is(err, io.EOF) {
+		return nil, &json.SyntaxError{Offset: dec.InputOffset()}
+	}
+	return n, nil
+}
+
+func parseJSONValue(dec *json.Decoder, data []byte) (*jsonNode, error) {
+	n := &jsonNode{off: tokenStart(data, dec.InputOffset())}
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		n.scalar = tok
+		return n, nil
+	}
+	switch delim {
+	case '{':
+		n.kind = jsonObject
+		for dec.More() {
+			off := tokenStart(data, dec.InputOffset())
+			key, keyErr := dec.Token()
+			if keyErr != nil {
+				return nil, keyErr
+			}
+			value, valErr := parseJSONValue(dec, data)
+			if valErr != nil {
+				return nil, valErr
+			}
+			n.fields = append(n.fields, jsonField{key: key.(string), off: off, value: value})
+		}
+	case '[':
+		n.kind = jsonArray
+		for dec.More() {
+			item, itemErr := parseJSONValue(dec, data)
+			if itemErr != nil {
+				return nil, itemErr
+			}
+			n.items = append(n.items, item)
+		}
+	}
+	if _, err = dec.Token(); err != nil { // closing delimiter
+		return nil, err
+	}
+	return n, nil
+}
+
+// tokenStart skips whitespace and separators from the decoder offset to the start of the next token.
+func tokenStart(data []byte, off int64) int64 {
+	for off < int64(len(data)) && strings.IndexByte(" \t\r\n,:", data[off]) >= 0 {
+		off++
+	}
+	return off
+}
@@ -0,0 +1,166 @@
+package routegroup_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-pkgz/routegroup"
+)
+
+func TestLoadConfig(t *testing.T) {
+	text := func(s string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { _, _ = w.Write([]byte(s)) })
+	}
+	header := func(name string) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Add("X-Mw", name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	reg := routegroup.Registry{
+		Handlers: map[string]http.Handler{
+			"health": text("ok"), "users": text("users"), "index": text("index"),
+			"login": text("login"), "notFound": http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				http.Error(w, "custom 404", http.StatusNotFound)
+			}),
+		},
+		Middlewares: map[string]func(http.Handler) http.Handler{"log": header("log"), "auth": header("auth")},
+	}
+
+	t.Run("builds tree", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, "app.css"), []byte("body{}"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		cfg := `{
+  "middlewares": ["log"],
+  "not_found": "notFound",
+  "routes": [{"pattern": "GET /health", "handler": "health"}],
+  "groups": [{
+    "mount": "/api",
+    "middlewares": ["auth"],
+    "root": {"method": "GET", "handler": "index"},
+    "routes": [
+      {"pattern": "GET /users", "handler": "users"},
+      {"pattern": "POST /login", "handler": "login", "without": ["auth"]}
+    ],
+    "files": [{"pattern": "/static/", "dir": "` + filepath.ToSlash(dir) + `"}]
+  }]
+}`
+		rtr := routegroup.New(http.NewServeMux())
+		if err := rtr.LoadConfig(strings.NewReader(cfg), reg); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		tbl := []struct {
+			method, path, body string
+			status             int
+			mws                []string
+		}{
+			{"GET", "/health", "ok", http.StatusOK, []string{"log"}},
+			{"GET", "/api", "index", http.StatusOK, []string{"log", "auth"}},
+			{"GET", "/api/users", "users", http.StatusOK, []string{"log", "auth"}},
+			{"POST", "/api/login", "login", http.StatusOK, []string{"log"}},
+			{"GET", "/api/static/app.css", "body{}", http.StatusOK, []string{"log", "auth"}},
+			{"GET", "/nope", "custom 404\n", http.StatusNotFound, []string{"log"}},
+		}
+		for _, tt := range tbl {
+			rec := httptest.NewRecorder()
+			rtr.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, http.NoBody))
+			if rec.Code != tt.status || rec.Body.String() != tt.body {
+				t.Errorf("%s %s: got %d %q, want %d %q", tt.method, tt.path, rec.Code, rec.Body.String(), tt.status, tt.body)
+			}
+			if got := rec.Header().Values("X-Mw"); !reflect.DeepEqual(got, tt.mws) {
+				t.Errorf("%s %s: middlewares %v, want %v", tt.method, tt.path, got, tt.mws)
+			}
+		}
+
+		for _, ri := range rtr.Routes() {
+			if ri.Pattern == "POST /api/login" && !reflect.DeepEqual(ri.Middlewares, []string{"log"}) {
+				t.Errorf("login middlewares: %v", ri.Middlewares)
+			}
+		}
+	})
+
+	t.Run("policies", func(t *testing.T) {
+		cfg := `{
+  "require": [{"prefix": "/api", "middlewares": ["auth"]}],
+  "groups": [{"mount": "/api", "routes": [{"pattern": "GET /users", "handler": "users"}]}]
+}`
+		rtr := routegroup.New(http.NewServeMux())
+		if err := rtr.LoadConfig(strings.NewReader(cfg), reg); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var verr *routegroup.ValidationError
+		if err := rtr.Validate(); !errors.As(err, &verr) || len(verr.Violations) != 1 {
+			t.Errorf("expected one violation, got %v", err)
+		}
+	})
+
+	t.Run("errors with positions", func(t *testing.T) {
+		tbl := []struct {
+			name, cfg, want string
+		}{
+			{"syntax", "{\n  \"routes\": [\n}", "config:3:1: invalid character '}'"},
+			{"unexpected eof", "{\"routes\": [", "config:1:13: unexpected end of JSON input"},
+			{"unknown handler", "{\n  \"routes\": [\n    {\"pattern\": \"GET /x\", \"handler\": \"missing\"}\n  ]\n}",
+				"config:3:38: unknown handler \"missing\""},
+			{"unknown middleware", "{\"groups\": [{\"middlewares\": [\"log\", \"nope\"]}]}",
+				"config:1:37: unknown middleware \"nope\""},
+			{"unknown group without", "{\"groups\": [{\"without\": [\"missing\"]}]}", "config:1:26: unknown middleware \"missing\""},
+			{"unknown route without", "{\"routes\": [{\"pattern\": \"GET /x\", \"handler\": \"users\", \"without\": [\"nope\"]}]}",
+				"config:1:67: unknown middleware \"nope\""},
+			{"unknown field", "{\n\t\"groups\": [{\"mount\": \"/a\", \"mnt\": 1}]\n}", "config:2:29: unknown field \"mnt\""},
+			{"mount at top level", "{\"mount\": \"/a\"}", "config:1:2: unknown field \"mount\""},
+			{"wrong type", "{\"routes\": {}}", "config:1:12: expected array"},
+			{"no handler", "{\"routes\": [{\"pattern\": \"GET /x\"}]}", "config:1:13: route has no handler"},
+			{"duplicate route", "{\"routes\": [\n {\"pattern\": \"GET /x\", \"handler\": \"users\"},\n {\"pattern\": \"GET /x\", \"handler\": \"users\"}\n]}",
+				"config:3:2: pattern \"GET /x\""},
+		}
+		for _, tt := range tbl {
+			t.Run(tt.name, func(t *testing.T) {
+				err := routegroup.New(http.NewServeMux()).LoadConfig(strings.NewReader(tt.cfg), reg)
+				var cerr *routegroup.ConfigError
+				if !errors.As(err, &cerr) {
+					t.Fatalf("expected ConfigError, got %v", err)
+				}
+				if !strings.HasPrefix(err.Error(), tt.want) {
+					t.Errorf("got %q, want prefix %q", err.Error(), tt.want)
+				}
+			})
+		}
+	})
+
+	t.Run("without middleware added in code", func(t *testing.T) {
+		rtr := routegroup.New(http.NewServeMux())
+		rtr.Use(routegroup.Named("metrics", header("metrics")))
+		cfg := `{"routes": [{"pattern": "GET /health", "handler": "health", "without": ["metrics"]}]}`
+		if err := rtr.LoadConfig(strings.NewReader(cfg), reg); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		rec := httptest.NewRecorder()
+		rtr.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", http.NoBody))
+		if rec.Body.String() != "ok" || rec.Header().Get("X-Mw") != "" {
+			t.Errorf("unexpected response %q %v", rec.Body.String(), rec.Header())
+		}
+	})
+
+	t.Run("file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "routes.json")
+		if err := os.WriteFile(path, []byte(`{"routes": [{"pattern": "GET /x", "handler": "bad"}]}`), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		err := routegroup.New(http.NewServeMux()).LoadConfigFile(path, reg)
+		if err == nil || !strings.HasPrefix(err.Error(), path+":1:46: unknown handler") {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+}
@@ -0,0 +1,139 @@
+package routegroup
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Consumes returns a new Group for routes accepting requests with the given content types only, e.g.
+// "application/json" or "multipart/form-data". Types may have a wildcard subtype, e.g. "image/*".
+// Other requests to such a route get 415, including requests without Content-Type. Routes for the same
+// pattern can consume different types, so "POST /items" can have separate JSON and multipart handlers.
+// A route registered for the pattern without content types serves requests not matching any of them.
+// The innermost Consumes wins. Content types of routes are reported by Routes.
+func (b *Bundle) Consumes(contentType string, more ...string) *Bundle {
+	types := normalizeMediaTypes(append([]string{contentType}, more...), true)
+	mu := b.treeLock()
+	mu.Lock()
+	defer mu.Unlock()
+	nb := b.clone()
+	nb.consumes = types
+	return nb
+}
+
+// Produces returns a new Group for routes responding with the given content types, e.g. "application/json".
+// Requests with the Accept header not accepting any of them get 406, requests without Accept are served.
+// Routes for the same pattern can produce different types; the route with the type of the highest quality
+// in the Accept header serves the request, the first registered one for equal qualities. A route registered
+// for the pattern without produced types serves requests not matching any of them. Handlers still set
+// the Content-Type of their responses. The innermost Produces wins. Produced types of routes are reported
+// by Routes.
+func (b *Bundle) Produces(contentType string, more ...string) *Bundle {
+	types := normalizeMediaTypes(append([]string{contentType}, more...), false)
+	mu := b.treeLock()
+	mu.Lock()
+	defer mu.Unlock()
+	nb := b.clone()
+	nb.produces = types
+	return nb
+}
+
+// contentSelectors returns the selectors for content types set with Consumes and Produces.
+func (b *Bundle) contentSelectors() []selector {
+	var res []selector
+	if consumes := b.consumes; len(consumes) > 0 {
+		res = append(res, selector{
+			key: "consumes " + strings.Join(slices.Sorted(slices.Values(consumes)), " "),
+			match: func(r *http.Request) int {
+				mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+				if err == nil && slices.ContainsFunc(consumes, func(t string) bool { return mediaTypeMatch(t, mt) }) {
+					return 0
+				}
+				return http.StatusUnsupportedMediaType
+			},
+		})
+	}
+	if produces := b.produces; len(produces) > 0 {
+		res = append(res, selector{
+			key: "produces " + strings.Join(slices.Sorted(slices.Values(produces)), " "),
+			match: func(r *http.Request) int {
+				if acceptQuality(r, produces) > 0 {
+					return 0
+				}
+				return http.StatusNotAcceptable
+			},
+			vary:    func(h http.Header) { addVary(h, "Accept") },
+			quality: func(r *http.Request) float64 { return acceptQuality(r, produces) },
+		})
+	}
+	return res
+}
+
+// acceptQuality returns the highest quality the Accept header of the request gives to any of the types,
+// 1 if the request has no Accept header. The quality of a type is set by the most specific media range
+// matching it, e.g. "text/html" before "text/*" before "*/*".
+func acceptQuality(r *http.Request, types []string) float64 {
+	accept := r.Header.Values("Accept")
+	if len(accept) == 0 {
+		return 1
+	}
+	best := 0.0
+	for _, t := range types {
+		quality, specificity := 0.0, -1
+		for _, header := range accept {
+			for _, mediaRange := range strings.Split(header, ",") {
+				mt, params, err := mime.ParseMediaType(mediaRange)
+				if err != nil || !mediaTypeMatch(mt, t) {
+					continue
+				}
+				s := 2 // exact type
+				switch {
+				case mt == "*/*":
+					s = 0
+				case strings.HasSuffix(mt, "/*"):
+					s = 1
+				}
+				if s <= specificity {
+					continue
+				}
+				q := 1.0
+				if v, ok := params["q"]; ok {
+					if q, err = strconv.ParseFloat(v, 64); err != nil {
+						q = 0
+					}
+				}
+				quality, specificity = q, s
+			}
+		}
+		best = max(best, quality)
+	}
+	return best
+}
+
+// mediaTypeMatch reports whether the media type matches the pattern, which may be "*/*" or
+// have a wildcard subtype, e.g. "image/*". Both are lower case without parameters.
+func mediaTypeMatch(pattern, mediaType string) bool {
+	if pattern == "*/*" || pattern == mediaType {
+		return true
+	}
+	prefix, ok := strings.CutSuffix(pattern, "*")
+	return ok && strings.HasSuffix(prefix, "/") && strings.HasPrefix(mediaType, prefix)
+}
+
+// normalizeMediaTypes returns the media types in lower case without parameters,
+// panicking for invalid types and for wildcards if they are not allowed.
+func normalizeMediaTypes(types []string, wildcards bool) []string {
+	res := make([]string, 0, len(types))
+	for _, t := range types {
+		mt, _, err := mime.ParseMediaType(t)
+		if err != nil || !strings.Contains(mt, "/") || (!wildcards && strings.Contains(mt, "*")) {
+			panic(fmt.Sprintf("routegroup: invalid media type %q", t))
+		}
+		res = append(res, mt)
+	}
+	return res
+}
@@ -0,0 +1,161 @@
+package routegroup_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/go-pkgz/routegroup"
+)
+
+func TestConsumesProduces(t *testing.T) {
+	reply := func(s string) http.HandlerFunc {
+		return func(w http.ResponseWriter, _ *http.Request) { _, _ = w.Write([]byte(s)) }
+	}
+
+	rtr := routegroup.New(http.NewServeMux())
+	api := rtr.Mount("/api")
+	api.Consumes("application/json").HandleFunc("POST /items", reply("json"))
+	api.Consumes("multipart/form-data").HandleFunc("POST /items", reply("multipart"))
+	api.Consumes("image/*").HandleFunc("PUT /items/{id}/image", reply("image"))
+	api.Produces("application/json").HandleFunc("GET /items", reply("json list"))
+	api.Produces("text/html").HandleFunc("GET /items", reply("html list"))
+	api.Produces("text/csv").HandleFunc("GET /export", reply("csv"))
+	api.HandleFunc("POST /notes", reply("any note"))
+	api.Consumes("application/json").HandleFunc("POST /notes", reply("json note"))
+
+	tbl := []struct {
+		name, method, path string
+		header             http.Header
+		code               int
+		body               string
+	}{
+		{name: "json", method: http.MethodPost, path: "/api/items", header: http.Header{"Content-Type": {"application/json"}},
+			code: http.StatusOK, body: "json"},
+		{name: "json with charset", method: http.MethodPost, path: "/api/items",
+			header: http.Header{"Content-Type": {"Application/JSON; charset=utf-8"}}, code: http.StatusOK, body: "json"},
+		{name: "multipart", method: http.MethodPost, path: "/api/items",
+			header: http.Header{"Content-Type": {"multipart/form-data; boundary=xyz"}}, code: http.StatusOK, body: "multipart"},
+		{name: "unsupported", method: http.MethodPost, path: "/api/items", header: http.Header{"Content-Type": {"text/plain"}},
+			code: http.StatusUnsupportedMediaType},
+		{name: "no content type", method: http.MethodPost, path: "/api/items", code: http.StatusUnsupportedMediaType},
+		{name: "wildcard subtype", method: http.MethodPut, path: "/api/items/1/image", header: http.Header{"Content-Type": {"image/png"}},
+			code: http.StatusOK, body: "image"},
+		{name: "wildcard mismatch", method: http.MethodPut, path: "/api/items/1/image", header: http.Header{"Content-Type": {"video/mp4"}},
+			code: http.StatusUnsupportedMediaType},
+		{name: "no accept", method: http.MethodGet, path: "/api/items", code: http.StatusOK, body: "json list"},
+		{name: "accept html", method: http.MethodGet, path: "/api/items", header: http.Header{"Accept": {"text/html"}},
+			code: http.StatusOK, body: "html list"},
+		{name: "accept quality", method: http.MethodGet, path: "/api/items",
+			header: http.Header{"Accept": {"application/json;q=0.5, text/html;q=0.9"}}, code: http.StatusOK, body: "html list"},
+		{name: "accept specific range wins", method: http.MethodGet, path: "/api/items",
+			header: http.Header{"Accept": {"text/*;q=0.1, application/*;q=0.5, text/html;q=1"}}, code: http.StatusOK, body: "html list"},
+		{name: "accept any", method: http.MethodGet, path: "/api/items", header: http.Header{"Accept": {"*/*"}},
+			code: http.StatusOK, body: "json list"},
+		{name: "not acceptable", method: http.MethodGet, path: "/api/items", header: http.Header{"Accept": {"application/xml"}},
+			code: http.StatusNotAcceptable},
+		{name: "excluded with q=0", method: http.MethodGet, path: "/api/export", header: http.Header{"Accept": {"text/*, text/csv;q=0"}},
+			code: http.StatusNotAcceptable},
+		{name: "fallback", method: http.MethodPost, path: "/api/notes", header: http.Header{"Content-Type": {"text/plain"}},
+			code: http.StatusOK, body: "any note"},
+		{name: "constrained before fallback", method: http.MethodPost, path: "/api/notes",
+			header: http.Header{"Content-Type": {"application/json"}}, code: http.StatusOK, body: "json note"},
+		{name: "method not allowed", method: http.MethodDelete, path: "/api/items", code: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader("{}"))
+			for k, v := range tt.header {
+				req.Header[k] = v
+			}
+			rec := httptest.NewRecorder()
+			rtr.ServeHTTP(rec, req)
+			if rec.Code != tt.code {
+				t.Errorf("expected status %d, got %d", tt.code, rec.Code)
+			}
+			if tt.body != "" && rec.Body.String() != tt.body {
+				t.Errorf("expected body %q, got %q", tt.body, rec.Body.String())
+			}
+		})
+	}
+
+	t.Run("vary", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		rtr.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/items", http.NoBody))
+		if vary := rec.Header().Values("Vary"); len(vary) != 1 || vary[0] != "Accept" {
+			t.Errorf("unexpected Vary %v", vary)
+		}
+	})
+
+	t.Run("routes", func(t *testing.T) {
+		var got []string
+		for _, ri := range rtr.Routes() {
+			if ri.Pattern == "POST /api/items" || ri.Pattern == "GET /api/items" {
+				got = append(got, ri.Pattern+" "+strings.Join(ri.Consumes, ",")+" "+strings.Join(ri.Produces, ","))
+			}
+		}
+		want := []string{"POST /api/items application/json ", "POST /api/items multipart/form-data ",
+			"GET /api/items  application/json", "GET /api/items  text/html"}
+		if !slices.Equal(got, want) {
+			t.Errorf("unexpected routes %q", got)
+		}
+	})
+}
+
+func TestConsumesWithVersion(t *testing.T) {
+	ok := func(w http.ResponseWriter, _ *http.Request) { _, _ = w.Write([]byte("ok")) }
+	rtr := routegroup.New(http.NewServeMux())
+	rtr.Version("v1").Consumes("application/json").HandleFunc("POST /items", ok)
+	rtr.Version("v2").Consumes("application/json").HandleFunc("POST /items", ok)
+
+	serve := func(path, version, contentType string) int {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader("{}"))
+		req.Header.Set("API-Version", version)
+		req.Header.Set("Content-Type", contentType)
+		rec := httptest.NewRecorder()
+		rtr.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := serve("/items", "v2", "application/json"); code != http.StatusOK {
+		t.Errorf("expected 200, got %d", code)
+	}
+	if code := serve("/items", "v2", "text/plain"); code != http.StatusUnsupportedMediaType {
+		t.Errorf("expected 415 for the version served with another content type, got %d", code)
+	}
+	if code := serve("/items", "v3", "application/json"); code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown version, got %d", code)
+	}
+	if code := serve("/v1/items", "", "text/plain"); code != http.StatusUnsupportedMediaType {
+		t.Errorf("expected 415 for the version path, got %d", code)
+	}
+}
+
+func TestContentTypePanics(t *testing.T) {
+	ok := func(http.ResponseWriter, *http.Request) {}
+	expectPanic := func(t *testing.T, fn func()) {
+		t.Helper()
+		defer func() {
+			if recover() == nil {
+				t.Error("expected panic")
+			}
+		}()
+		fn()
+	}
+
+	t.Run("invalid media type", func(t *testing.T) {
+		expectPanic(t, func() { routegroup.New(http.NewServeMux()).Consumes("json") })
+	})
+
+	t.Run("wildcard produces", func(t *testing.T) {
+		expectPanic(t, func() { routegroup.New(http.NewServeMux()).Produces("text/*") })
+	})
+
+	t.Run("duplicate content type", func(t *testing.T) {
+		rtr := routegroup.New(http.NewServeMux())
+		rtr.Consumes("application/json", "text/plain").HandleFunc("POST /items", ok)
+		expectPanic(t, func() { rtr.Consumes("text/plain", "application/json").HandleFunc("POST /items", ok) })
+	})
+}
@@ -0,0 +1,170 @@
+package routegroup
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSOpts configures cross-origin resource sharing for a group, see Bundle.CORS.
+type CORSOpts struct {
+	// AllowedOrigins lists origins allowed to make cross-origin requests: exact origins, e.g.
+	// "https://app.example.com", wildcard subdomains, e.g. "https://*.example.com", or "*" for any origin.
+	AllowedOrigins []string
+
+	// AllowOriginFunc allows origins not listed in AllowedOrigins, if set.
+	AllowOriginFunc func(origin string) bool
+
+	// AllowedHeaders lists request headers allowed in preflight requests. If empty, headers
+	// requested by the preflight are allowed.
+	AllowedHeaders []string
+
+	ExposedHeaders   []string      // response headers exposed to the client script
+	AllowCredentials bool          // allow cookies and HTTP authentication
+	MaxAge           time.Duration // how long preflight responses can be cached, not sent if zero
+}
+
+// corsPolicy is CORSOpts prepared for matching, shared by routes registered on the group.
+type corsPolicy struct {
+	opts      CORSOpts
+	any       bool
+	exact     []string
+	wildcards [][2]string // scheme with "://" and host suffix with the leading dot
+}
+
+// corsMethods are methods checked for a path to answer preflight requests.
+var corsMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete,
+}
+
+// CORS returns a new Group with cross-origin resource sharing enabled for routes registered on it and
+// groups derived from it. Responses to cross-origin requests from allowed origins get CORS headers.
+// Preflight requests are answered automatically for paths of the group's routes, with
+// Access-Control-Allow-Methods listing the methods actually registered for the path, so there is no
+// need to register OPTIONS routes. Preflights are answered before routing, so OPTIONS routes and catch-all
+// routes, e.g. HandleFiles("/", ...), don't get them. Preflights from origins not allowed are rejected with 403.
+// The CORS middleware is named "cors" and runs after middlewares already added to the group.
+func (b *Bundle) CORS(opts CORSOpts) *Bundle {
+	p := &corsPolicy{opts: opts}
+	for _, o := range opts.AllowedOrigins {
+		switch scheme, host, ok := strings.Cut(strings.ToLower(o), "://*."); {
+		case o == "*":
+			p.any = true
+		case ok:
+			p.wildcards = append(p.wildcards, [2]string{scheme + "://", "." + host})
+		default:
+			p.exact = append(p.exact, strings.ToLower(o))
+		}
+	}
+
+	mu := b.treeLock()
+	mu.Lock()
+	defer mu.Unlock()
+	nb := b.clone()
+	nb.cors = p
+	nb.middlewares = append(nb.middlewares, &middleware{fn: Named("cors", func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p.setHeaders(w, r)
+			next.ServeHTTP(w, r)
+		})
+	})})
+	return nb
+}
+
+// allowed reports whether the origin is allowed.
+func (p *corsPolicy) allowed(origin string) bool {
+	if p.any {
+		return true
+	}
+	o := strings.ToLower(origin)
+	if slices.Contains(p.exact, o) {
+		return true
+	}
+	for _, wc := range p.wildcards {
+		if host, ok := strings.CutPrefix(o, wc[0]); ok && strings.HasSuffix(host, wc[1]) {
+			return true
+		}
+	}
+	return p.opts.AllowOriginFunc != nil && p.opts.AllowOriginFunc(origin)
+}
+
+// setHeaders sets CORS headers of a response to an allowed origin, returning false if the origin is not allowed.
+func (p *corsPolicy) setHeaders(w http.ResponseWriter, r *http.Request) bool {
+	h := w.Header()
+	h.Add("Vary", "Origin")
+	origin := r.Header.Get("Origin")
+	if origin == "" || !p.allowed(origin) {
+		return false
+	}
+	if p.any && !p.opts.AllowCredentials {
+		h.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		h.Set("Access-Control-Allow-Origin", origin)
+	}
+	if p.opts.AllowCredentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+	if len(p.opts.ExposedHeaders) > 0 && r.Method != http.MethodOptions {
+		h.Set("Access-Control-Expose-Headers", strings.Join(p.opts.ExposedHeaders, ", "))
+	}
+	return true
+}
+
+func isPreflight(r *http.Request) bool {
+	return r.Method == http.MethodOptions && r.Header.Get("Origin") != "" &&
+		r.Header.Get("Access-Control-Request-Method") != ""
+}
+
+// corsPreflight reports whether the request is a preflight for a route with CORS enabled.
+func (b *Bundle) corsPreflight(r *http.Request) bool {
+	return isPreflight(r) && b.corsRoute(r, r.Header.Get("Access-Control-Request-Method")) != nil
+}
+
+// preflight answers a preflight request if the requested method matches a route with CORS enabled,
+// returning false otherwise. Allowed methods are found by matching the request path with the mux
+// for each of corsMethods.
+func (b *Bundle) preflight(w http.ResponseWriter, r *http.Request) bool {
+	p := b.corsRoute(r, r.Header.Get("Access-Control-Request-Method"))
+	if p == nil {
+		return false
+	}
+	if !p.setHeaders(w, r) {
+		b.renderError(w, r, http.StatusForbidden)
+		return true
+	}
+
+	var methods []string
+	for _, m := range corsMethods {
+		if b.corsRoute(r, m) != nil {
+			methods = append(methods, m)
+		}
+	}
+	h := w.Header()
+	h.Add("Vary", "Access-Control-Request-Method")
+	h.Add("Vary", "Access-Control-Request-Headers")
+	h.Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+	if len(p.opts.AllowedHeaders) > 0 {
+		h.Set("Access-Control-Allow-Headers", strings.Join(p.opts.AllowedHeaders, ", "))
+	} else if reqHeaders := r.Header.Get("Access-Control-Request-Headers"); reqHeaders != "" {
+		h.Set("Access-Control-Allow-Headers", reqHeaders)
+	}
+	if p.opts.MaxAge > 0 {
+		h.Set("Access-Control-Max-Age", strconv.Itoa(int(p.opts.MaxAge.Seconds())))
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return true
+}
+
+// corsRoute returns the CORS policy of the route matching the request path with the given method,
+// nil if there is no such route or it has no CORS enabled.
+func (b *Bundle) corsRoute(r *http.Request, method string) *corsPolicy {
+	probe := *r
+	probe.Method = method
+	h, _ := b.mux.Handler(&probe)
+	if rt, ok := h.(*route); ok {
+		return rt.cors
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package routegroup_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-pkgz/routegroup"
+)
+
+func TestCORS(t *testing.T) {
+	ok := func(w http.ResponseWriter, _ *http.Request) { _, _ = w.Write([]byte("ok")) }
+	rtr := routegroup.New(http.NewServeMux())
+	api := rtr.Mount("/api").CORS(routegroup.CORSOpts{
+		AllowedOrigins:   []string{"https://app.example.com", "https://*.example.org"},
+		AllowOriginFunc:  func(origin string) bool { return origin == "http://localhost:3000" },
+		ExposedHeaders:   []string{"X-Total"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	})
+	api.HandleFunc("GET /users/{id}", ok)
+	api.HandleFunc("PUT /users/{id}", ok)
+	api.HandleFunc("/any", ok)                   // all methods, including OPTIONS
+	rtr.HandleFunc("DELETE /api/users/{id}", ok) // outside the CORS group
+	rtr.Mount("/public").CORS(routegroup.CORSOpts{AllowedOrigins: []string{"*"}, AllowedHeaders: []string{"X-Token"}}).
+		HandleFunc("GET /feed", ok)
+
+	preflight := func(path, origin, method string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodOptions, path, http.NoBody)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", method)
+		req.Header.Set("Access-Control-Request-Headers", "content-type")
+		rec := httptest.NewRecorder()
+		rtr.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("preflight with registered methods", func(t *testing.T) {
+		rec := preflight("/api/users/1", "https://app.example.com", "PUT")
+		want := map[string]string{
+			"Access-Control-Allow-Origin":      "https://app.example.com",
+			"Access-Control-Allow-Methods":     "GET, HEAD, PUT",
+			"Access-Control-Allow-Headers":     "content-type",
+			"Access-Control-Allow-Credentials": "true",
+			"Access-Control-Max-Age":           "600",
+			"Access-Control-Expose-Headers":    "",
+		}
+		if rec.Code != http.StatusNoContent {
+			t.Errorf("unexpected status %d", rec.Code)
+		}
+		for k, v := range want {
+			if got := rec.Header().Get(k); got != v {
+				t.Errorf("%s: got %q, want %q", k, got, v)
+			}
+		}
+	})
+
+	t.Run("preflight origins", func(t *testing.T) {
+		tbl := []struct {
+			origin string
+			status int
+		}{
+			{"https://app.example.com", http.StatusNoContent},
+			{"https://APP.example.com", http.StatusNoContent},
+			{"https://a.b.example.org", http.StatusNoContent},
+			{"https://example.org", http.StatusForbidden},
+			{"http://x.example.org", http.StatusForbidden},
+			{"http://localhost:3000", http.StatusNoContent},
+			{"https://evil.com", http.StatusForbidden},
+		}
+		for _, tt := range tbl {
+			rec := preflight("/api/users/1", tt.origin, "GET")
+			if rec.Code != tt.status {
+				t.Errorf("%s: got %d, want %d", tt.origin, rec.Code, tt.status)
+			}
+			if tt.status == http.StatusForbidden && rec.Header().Get("Access-Control-Allow-Origin") != "" {
+				t.Errorf("%s: unexpected allow origin header", tt.origin)
+			}
+		}
+	})
+
+	t.Run("preflight for method outside the group", func(t *testing.T) {
+		rec := preflight("/api/users/1", "https://app.example.com", "DELETE")
+		if rec.Code != http.StatusMethodNotAllowed || rec.Header().Get("Access-Control-Allow-Origin") != "" {
+			t.Errorf("unexpected response %d %v", rec.Code, rec.Header())
+		}
+	})
+
+	t.Run("preflight for route matching all methods", func(t *testing.T) {
+		rec := preflight("/api/any", "https://app.example.com", "POST")
+		if rec.Code != http.StatusNoContent || rec.Header().Get("Access-Control-Allow-Methods") != "GET, HEAD, POST, PUT, PATCH, DELETE" {
+			t.Errorf("unexpected response %d %v", rec.Code, rec.Header())
+		}
+	})
+
+	t.Run("wildcard origin without credentials", func(t *testing.T) {
+		rec := preflight("/public/feed", "https://any.site", "GET")
+		if rec.Header().Get("Access-Control-Allow-Origin") != "*" || rec.Header().Get("Access-Control-Allow-Headers") != "X-Token" ||
+			rec.Header().Get("Access-Control-Max-Age") != "" {
+			t.Errorf("unexpected headers %v", rec.Header())
+		}
+	})
+
+	t.Run("actual requests", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/users/1", http.NoBody)
+		req.Header.Set("Origin", "https://app.example.com")
+		rec := httptest.NewRecorder()
+		rtr.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK || rec.Header().Get("Access-Control-Allow-Origin") != "https://app.example.com" ||
+			rec.Header().Get("Access-Control-Expose-Headers") != "X-Total" || rec.Header().Get("Vary") != "Origin" {
+			t.Errorf("unexpected response %d %v", rec.Code, rec.Header())
+		}
+
+		req.Header.Set("Origin", "https://evil.com")
+		rec = httptest.NewRecorder()
+		rtr.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK || rec.Header().Get("Access-Control-Allow-Origin") != "" {
+			t.Errorf("unexpected response %d %v", rec.Code, rec.Header())
+		}
+	})
+
+	t.Run("plain options is not a preflight", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		rtr.ServeHTTP(rec, httptest.NewRequest(http.MethodOptions, "/api/users/1", http.NoBody))
+		if rec.Code != http.StatusMethodNotAllowed {
+			t.Errorf("unexpected status %d", rec.Code)
+		}
+	})
+
+	t.Run("preflight with catch-all route", func(t *testing.T) {
+		files := routegroup.New(http.NewServeMux())
+		files.Mount("/api").CORS(routegroup.CORSOpts{AllowedOrigins: []string{"https://app.example.com"}}).
+			HandleFunc("PUT /users/{id}", ok)
+		files.HandleFiles("/", http.Dir("."))
+
+		req := httptest.NewRequest(http.MethodOptions, "/api/users/1", http.NoBody)
+		req.Header.Set("Origin", "https://app.example.com")
+		req.Header.Set("Access-Control-Request-Method", "PUT")
+		rec := httptest.NewRecorder()
+		files.ServeHTTP(rec, req)
+		if rec.Code != http.StatusNoContent || rec.Header().Get("Access-Control-Allow-Origin") != "https://app.example.com" ||
+			rec.Header().Get("Access-Control-Allow-Methods") != "PUT" {
+			t.Errorf("unexpected response %d %v", rec.Code, rec.Header())
+		}
+
+		req.Header.Set("Access-Control-Request-Method", "GET") // served by the catch-all route without CORS
+		rec = httptest.NewRecorder()
+		files.ServeHTTP(rec, req)
+		if rec.Header().Get("Access-Control-Allow-Origin") != "" {
+			t.Errorf("unexpected headers %v", rec.Header())
+		}
+	})
+
+	t.Run("introspection", func(t *testing.T) {
+		for _, ri := range rtr.Routes() {
+			if ri.Pattern == "GET /api/users/{id}" && (len(ri.Middlewares) != 1 || ri.Middlewares[0] != "cors") {
+				t.Errorf("unexpected middlewares %v", ri.Middlewares)
+			}
+		}
+	})
+}
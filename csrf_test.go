@@ -0,0 +1,90 @@
+package routegroup_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-pkgz/routegroup"
+)
+
+func TestCSRF(t *testing.T) {
+	ok := func(w http.ResponseWriter, _ *http.Request) { _, _ = w.Write([]byte("ok")) }
+	rtr := routegroup.New(http.NewServeMux())
+	admin := rtr.Mount("/admin").CSRF(routegroup.CSRFOpts{
+		TrustedOrigins: []string{"https://sso.example.com"},
+		Bypass:         []string{"POST /webhooks/{id}"},
+	})
+	admin.HandleFunc("GET /users", ok)
+	admin.HandleFunc("POST /users", ok)
+	admin.HandleFunc("POST /webhooks/{id}", ok)
+	rtr.HandleFunc("POST /public", ok) // outside the protected group
+
+	tbl := []struct {
+		name, method, path string
+		headers            map[string]string
+		status             int
+	}{
+		{"safe method cross-site", "GET", "/admin/users", map[string]string{"Sec-Fetch-Site": "cross-site"}, 200},
+		{"same origin", "POST", "/admin/users", map[string]string{"Sec-Fetch-Site": "same-origin"}, 200},
+		{"user initiated", "POST", "/admin/users", map[string]string{"Sec-Fetch-Site": "none"}, 200},
+		{"cross site", "POST", "/admin/users", map[string]string{"Sec-Fetch-Site": "cross-site", "Origin": "https://evil.com"}, 403},
+		{"same site", "POST", "/admin/users", map[string]string{"Sec-Fetch-Site": "same-site", "Origin": "https://other.example.com"}, 403},
+		{"trusted origin", "POST", "/admin/users", map[string]string{"Sec-Fetch-Site": "cross-site", "Origin": "https://SSO.example.com"}, 200},
+		{"bypass", "POST", "/admin/webhooks/1", map[string]string{"Sec-Fetch-Site": "cross-site"}, 200},
+		{"no fetch metadata, matching origin", "POST", "/admin/users", map[string]string{"Origin": "http://example.com"}, 200},
+		{"no fetch metadata, other origin", "POST", "/admin/users", map[string]string{"Origin": "http://evil.com"}, 403},
+		{"no fetch metadata, null origin", "POST", "/admin/users", map[string]string{"Origin": "null"}, 403},
+		{"not a browser", "POST", "/admin/users", nil, 200},
+		{"outside the group", "POST", "/public", map[string]string{"Sec-Fetch-Site": "cross-site"}, 200},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, http.NoBody) // host is example.com
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			rtr.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Errorf("got %d, want %d", rec.Code, tt.status)
+			}
+		})
+	}
+}
+
+func TestErrorHandler(t *testing.T) {
+	rtr := routegroup.New(http.NewServeMux())
+	rtr.ErrorHandler(func(w http.ResponseWriter, r *http.Request, status int) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = fmt.Fprintf(w, `{"error":%q,"pattern":%q}`, http.StatusText(status), r.Pattern)
+	})
+	admin := rtr.Mount("/admin").CSRF(routegroup.CSRFOpts{})
+	admin.HandleFunc("POST /users", func(http.ResponseWriter, *http.Request) {})
+	admin.HandleFunc("GET /report", func(http.ResponseWriter, *http.Request) {})
+	if err := rtr.DisableRoute("GET /admin/report", http.StatusServiceUnavailable); err != nil {
+		t.Fatal(err)
+	}
+
+	tbl := []struct {
+		name, method, path, body string
+		status                   int
+	}{
+		{"csrf", "POST", "/admin/users", `{"error":"Forbidden","pattern":"POST /admin/users"}`, http.StatusForbidden},
+		{"disabled route", "GET", "/admin/report", `{"error":"Service Unavailable","pattern":"GET /admin/report"}`,
+			http.StatusServiceUnavailable},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, http.NoBody)
+			req.Header.Set("Sec-Fetch-Site", "cross-site")
+			rec := httptest.NewRecorder()
+			rtr.ServeHTTP(rec, req)
+			if rec.Code != tt.status || rec.Body.String() != tt.body || rec.Header().Get("Content-Type") != "application/json" {
+				t.Errorf("unexpected response %d %q", rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
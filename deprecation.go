@@ -0,0 +1,82 @@
+package routegroup
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Deprecation describes a deprecated route or group, see Bundle.Deprecate.
+type Deprecation struct {
+	Date   time.Time // when the route was or will be deprecated, required
+	Sunset time.Time // when the route will stop working, optional
+	Link   string    // URL of a document describing the deprecation, e.g. a migration guide, optional
+
+	// Hook is called for each request to a deprecated route, e.g. to count calls.
+	// r.Pattern identifies the route.
+	Hook func(r *http.Request)
+}
+
+// Deprecate returns a new Group marking routes registered on it as deprecated. Their responses get
+// the Deprecation header (RFC 9745) with the date, the Sunset header (RFC 8594) if set and
+// a Link header with rel="deprecation" if a link is set, added to Link headers set by other middlewares.
+// The innermost deprecation wins: its headers replace all headers of enclosing deprecations, including
+// Sunset and Link it doesn't set, while hooks of all enclosing deprecations are called.
+// Deprecated routes are reported by Routes with their deprecation. The middleware is named "deprecation".
+func (b *Bundle) Deprecate(d Deprecation) *Bundle {
+	if d.Date.IsZero() {
+		panic("routegroup: deprecation needs a date")
+	}
+	deprecation := "@" + strconv.FormatInt(d.Date.Unix(), 10)
+	var sunset, link string
+	if !d.Sunset.IsZero() {
+		sunset = d.Sunset.UTC().Format(http.TimeFormat)
+	}
+	if d.Link != "" {
+		link = "<" + d.Link + `>; rel="deprecation"; type="text/html"`
+	}
+
+	mu := b.treeLock()
+	mu.Lock()
+	defer mu.Unlock()
+	nb := b.clone()
+	nb.middlewares = append(nb.middlewares, &middleware{deprecation: &d, fn: Named("deprecation", func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h := w.Header()
+			h.Set("Deprecation", deprecation)
+			h.Del("Sunset") // set by an outer deprecation
+			if sunset != "" {
+				h.Set("Sunset", sunset)
+			}
+			dropDeprecationLinks(h) // set by an outer deprecation, other links are kept
+			if link != "" {
+				h.Add("Link", link)
+			}
+			if d.Hook != nil {
+				d.Hook(r)
+			}
+			next.ServeHTTP(w, r)
+		})
+	})})
+	return nb
+}
+
+// dropDeprecationLinks removes Link header values with rel="deprecation" set by Deprecate.
+func dropDeprecationLinks(h http.Header) {
+	links := h.Values("Link")
+	if len(links) == 0 {
+		return
+	}
+	kept := make([]string, 0, len(links))
+	for _, l := range links {
+		if !strings.HasSuffix(l, `>; rel="deprecation"; type="text/html"`) {
+			kept = append(kept, l)
+		}
+	}
+	if len(kept) == 0 {
+		h.Del("Link")
+		return
+	}
+	h["Link"] = kept
+}
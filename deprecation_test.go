@@ -0,0 +1,80 @@
+package routegroup_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-pkgz/routegroup"
+)
+
+func TestDeprecate(t *testing.T) {
+	ok := func(w http.ResponseWriter, _ *http.Request) { _, _ = w.Write([]byte("ok")) }
+	var calls atomic.Int32
+	date := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	sunset := time.Date(2026, 12, 31, 23, 59, 59, 0, time.UTC)
+
+	rtr := routegroup.New(http.NewServeMux())
+	linked := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Link", `</api/v1/docs>; rel="help"`)
+			next.ServeHTTP(w, r)
+		})
+	}
+	v1 := rtr.Mount("/api/v1").With(linked).Deprecate(routegroup.Deprecation{
+		Date: date, Sunset: sunset, Link: "https://example.com/migrate",
+		Hook: func(r *http.Request) {
+			if r.Pattern == "" {
+				t.Error("no pattern in hook")
+			}
+			calls.Add(1)
+		},
+	})
+	v1.HandleFunc("GET /users", ok)
+	v1.Deprecate(routegroup.Deprecation{Date: date.AddDate(-1, 0, 0)}).HandleFunc("GET /legacy", ok)
+	rtr.Mount("/api/v2").HandleFunc("GET /users", ok)
+
+	serve := func(path string) http.Header {
+		rec := httptest.NewRecorder()
+		rtr.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, http.NoBody))
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s: unexpected status %d", path, rec.Code)
+		}
+		return rec.Header()
+	}
+
+	h := serve("/api/v1/users")
+	if h.Get("Deprecation") != "@1767225600" || h.Get("Sunset") != "Thu, 31 Dec 2026 23:59:59 GMT" ||
+		!reflect.DeepEqual(h.Values("Link"), []string{`</api/v1/docs>; rel="help"`,
+			`<https://example.com/migrate>; rel="deprecation"; type="text/html"`}) {
+		t.Errorf("unexpected headers %v", h)
+	}
+
+	h = serve("/api/v1/legacy") // nested deprecation wins, the group's sunset and link are dropped, other links kept
+	if h.Get("Deprecation") != "@1735689600" || h.Get("Sunset") != "" ||
+		!reflect.DeepEqual(h.Values("Link"), []string{`</api/v1/docs>; rel="help"`}) {
+		t.Errorf("unexpected headers %v", h)
+	}
+
+	h = serve("/api/v2/users")
+	if h.Get("Deprecation") != "" || h.Get("Sunset") != "" || h.Get("Link") != "" {
+		t.Errorf("unexpected headers %v", h)
+	}
+
+	if calls.Load() != 2 {
+		t.Errorf("expected 2 hook calls, got %d", calls.Load())
+	}
+
+	var deprecated []string
+	for _, ri := range rtr.Routes() {
+		if ri.Deprecation != nil {
+			deprecated = append(deprecated, ri.Pattern+" "+ri.Deprecation.Date.Format("2006"))
+		}
+	}
+	if len(deprecated) != 2 || deprecated[0] != "GET /api/v1/users 2026" || deprecated[1] != "GET /api/v1/legacy 2025" {
+		t.Errorf("unexpected deprecated routes %v", deprecated)
+	}
+}
@@ -0,0 +1,138 @@
+package routegroup_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-pkgz/routegroup"
+)
+
+func TestFreeze(t *testing.T) {
+	ok := func(w http.ResponseWriter, _ *http.Request) { _, _ = w.Write([]byte("ok")) }
+
+	t.Run("frozen router serves with precomputed chains", func(t *testing.T) {
+		var built, calls int
+		counting := func(next http.Handler) http.Handler {
+			built++
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				w.Header().Set("X-Root", r.Pattern)
+				next.ServeHTTP(w, r)
+			})
+		}
+
+		rtr := routegroup.New(http.NewServeMux())
+		rtr.Use(routegroup.Named("root", counting))
+		api := rtr.Mount("/api")
+		api.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("user " + r.PathValue("id")))
+		})
+		api.Without("root").HandleFunc("GET /public", ok)
+		rtr.NotFoundHandler(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte("custom 404"))
+		})
+
+		if err := rtr.Freeze(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		builtAtFreeze := built
+
+		tests := []struct {
+			method, path, body, rootHeader string
+			code                           int
+		}{
+			{http.MethodGet, "/api/users/1", "user 1", "GET /api/users/{id}", http.StatusOK},
+			{http.MethodGet, "/api/users/2", "user 2", "GET /api/users/{id}", http.StatusOK},
+			{http.MethodGet, "/api/public", "ok", "", http.StatusOK},
+			{http.MethodGet, "/nope", "custom 404", "", http.StatusNotFound},
+			{http.MethodPost, "/api/public", "Method Not Allowed\n", "", http.StatusMethodNotAllowed},
+		}
+		for _, tt := range tests {
+			rec := httptest.NewRecorder()
+			rtr.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, http.NoBody))
+			if rec.Code != tt.code {
+				t.Errorf("%s %s: expected status %d, got %d", tt.method, tt.path, tt.code, rec.Code)
+			}
+			if rec.Body.String() != tt.body {
+				t.Errorf("%s %s: expected body %q, got %q", tt.method, tt.path, tt.body, rec.Body.String())
+			}
+			if h := rec.Header().Get("X-Root"); h != tt.rootHeader {
+				t.Errorf("%s %s: expected X-Root %q, got %q", tt.method, tt.path, tt.rootHeader, h)
+			}
+		}
+		if built != builtAtFreeze {
+			t.Errorf("root middleware should not be rebuilt per request after Freeze, built %d times", built-builtAtFreeze)
+		}
+		if calls != 4 {
+			t.Errorf("expected root middleware to be called 4 times, got %d", calls)
+		}
+	})
+
+	t.Run("changes after freeze panic", func(t *testing.T) {
+		rtr := routegroup.New(http.NewServeMux())
+		api := rtr.Mount("/api")
+		api.HandleFunc("/a", ok)
+		if err := api.Freeze(); err != nil { // freezing from a child freezes the whole tree
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := rtr.Freeze(); err != nil {
+			t.Fatalf("second freeze should be a no-op, got %v", err)
+		}
+
+		changes := map[string]func(){
+			"root Use":        func() { rtr.Use(passThrough) },
+			"child Use":       func() { api.Use(passThrough) },
+			"UseIf":           func() { api.UseIf(func(*http.Request) bool { return true }, passThrough) },
+			"Handle":          func() { rtr.Handle("/b", http.HandlerFunc(ok)) },
+			"HandleFunc":      func() { api.HandleFunc("/b", ok) },
+			"HandleFiles":     func() { api.HandleFiles("/static/", http.Dir(".")) },
+			"HandleRoot":      func() { api.HandleRoot("GET", http.HandlerFunc(ok)) },
+			"HandleRootFunc":  func() { api.HandleRootFunc("GET", ok) },
+			"Mount":           func() { rtr.Mount("/x") },
+			"Group":           func() { api.Group() },
+			"With":            func() { api.With(passThrough) },
+			"Without":         func() { api.Without("auth") },
+			"Route":           func() { rtr.Route(func(*routegroup.Bundle) {}) },
+			"NotFoundHandler": func() { rtr.NotFoundHandler(ok) },
+			"Require":         func() { rtr.Require(routegroup.Policy{}) },
+		}
+		for name, fn := range changes {
+			t.Run(name, func(t *testing.T) {
+				defer func() {
+					if r := recover(); r == nil {
+						t.Errorf("%s after Freeze should panic", name)
+					}
+				}()
+				fn()
+			})
+		}
+
+		rec := httptest.NewRecorder()
+		rtr.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/a", http.NoBody))
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected status 200, got %d", rec.Code)
+		}
+	})
+
+	t.Run("validation failure keeps router unfrozen", func(t *testing.T) {
+		rtr := routegroup.New(http.NewServeMux())
+		rtr.Require(routegroup.Policy{Prefix: "/api", Middlewares: []string{"auth"}})
+		api := rtr.Mount("/api")
+		api.HandleFunc("/a", ok)
+
+		var verr *routegroup.ValidationError
+		if err := rtr.Freeze(); !errors.As(err, &verr) {
+			t.Fatalf("expected validation error, got %v", err)
+		}
+
+		// still possible to fix the routes and freeze
+		api.With(routegroup.Named("auth", passThrough)).HandleFunc("/b", ok)
+		api.Without("auth") // no panic, not frozen
+		if err := rtr.Freeze(); err == nil {
+			t.Fatal("expected validation error for /api/a")
+		}
+	})
+}
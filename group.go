@@ -6,13 +6,15 @@ import (
 	"net/http"
 	"regexp"
 	"strings"
+	"sync"
+	"sync/atomic"
 )
 
 // Bundle represents a group of routes with associated middleware.
 type Bundle struct {
-	mux         *http.ServeMux                    // the underlying mux to register the routes to
-	basePath    string                            // base path for the group
-	middlewares []func(http.Handler) http.Handler // middlewares stack
+	mux         *http.ServeMux // the underlying mux to register the routes to
+	basePath    string         // base path for the group
+	middlewares []*middleware  // middlewares stack
 
 	// optional custom 404 handler
 	notFound http.HandlerFunc
@@ -28,6 +30,47 @@ type Bundle struct {
 	// rootCount captures how many root middlewares were present when this bundle
 	// was created. Used to avoid double-applying root middlewares for per-route wrapping.
 	rootCount int
+
+	// skip lists root middlewares excluded with Without for routes registered on this bundle.
+	skip []*middleware
+
+	// cors is set by CORS for routes registered on this bundle and bundles derived from it.
+	cors *corsPolicy
+
+	// version is set by Version for routes registered on this bundle and bundles derived from it,
+	// versionBase is the base path the version prefix is added to.
+	version, versionBase string
+
+	// consumes and produces are set by Consumes and Produces for routes registered on this bundle
+	// and bundles derived from it.
+	consumes, produces []string
+
+	// routes registered on the bundle's tree, in registration order, and indexed by pattern.
+	// populated on the root bundle only.
+	routes []*route
+	index  map[string]*route
+
+	// policies added with Require, checked by Validate. populated on the root bundle only.
+	policies []Policy
+
+	// frozen is set on the root bundle by Freeze.
+	frozen bool
+
+	// tracer is set on the root bundle by SetTracer.
+	tracer Tracer
+
+	// versioning is set on the root bundle by Versioning.
+	versioning VersioningOpts
+
+	// errorHandler is set on the root bundle by ErrorHandler.
+	errorHandler func(w http.ResponseWriter, r *http.Request, status int)
+
+	// root middleware chains, composed on first use. populated on the root bundle only, see dispatcher.
+	dispatch atomic.Pointer[dispatch]
+
+	// mu guards changes of all bundles in the tree, so routes can be registered while serving.
+	// used on the root bundle only, see treeLock.
+	mu sync.Mutex
 }
 
 // New creates a new Group.
@@ -42,58 +85,157 @@ func Mount(mux *http.ServeMux, basePath string) *Bundle {
 
 // ServeHTTP implements the http.Handler interface
 func (b *Bundle) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	// resolve the root bundle (where global middlewares live).
-	root := b
-	if b.root != nil {
-		root = b.root
-	}
+	// resolve the root bundle (where global middlewares live) and its composed chains.
+	d := b.rootBundle().dispatcher()
 
-	// get the handler and pattern for this request
+	// get the pattern for this request
 	_, pattern := b.mux.Handler(r)
+	if pattern != "" && b.corsPreflight(r) {
+		// answered for the CORS route whatever OPTIONS matches, e.g. a catch-all route, see serveUnmatched
+		pattern = ""
+	}
 
-	// if a pattern was found, create a shallow copy of the request with the pattern set
+	if d.tracer != nil {
+		b.serveTraced(w, r, d, pattern)
+		return
+	}
+	d.serve(w, r, pattern)
+}
+
+// serve dispatches the request with the given matched pattern through the root middleware chains.
+func (d *dispatch) serve(w http.ResponseWriter, r *http.Request, pattern string) {
+	// unmatched requests go to the mux via the 404 interceptor
+	if pattern == "" {
+		d.unmatched.ServeHTTP(w, r)
+		return
+	}
+
+	// create a shallow copy of the request with the pattern set
 	// this allows global middlewares to see the pattern before mux.ServeHTTP is called
-	if pattern != "" {
-		r2 := *r
-		r2.Pattern = pattern
-		r = &r2
-	}
-
-	// create a handler that will let the mux do its routing (including setting path parameters)
-	// but intercept 404s to use custom handler if provided
-	muxHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if pattern == "" && root.notFound != nil {
-			// no route matched, need to check if it's a true 404 or a 405
-			// probe the mux to see what status it would return
-			probe := &statusRecorder{status: http.StatusOK}
-			b.mux.ServeHTTP(probe, r)
-
-			// if mux wants to return 405 (Method Not Allowed), let it handle the request
-			// to preserve the proper 405 response and Allow header
-			if probe.status == http.StatusMethodNotAllowed {
-				b.mux.ServeHTTP(w, r)
-				return
-			}
+	r2 := *r
+	r2.Pattern = pattern
+
+	// apply root (global) middlewares around the mux and serve the request,
+	// the mux does its routing, including setting path parameters.
+	h := d.chain
+	if len(d.chains) > 0 {
+		if c, ok := d.chains[pattern]; ok {
+			h = c
+		}
+	}
+	h.ServeHTTP(w, &r2)
+}
 
-			// it's a true 404, use custom handler
-			root.notFound.ServeHTTP(w, r)
-			return
+// dispatch keeps root middlewares composed around the mux for routes, and around the 404 interceptor
+// for requests without a matching route. It is immutable, changes of root middlewares or routes
+// replace it as a whole.
+type dispatch struct {
+	chain        http.Handler            // shared by all routes, including patterns registered on the mux directly
+	chains       map[string]http.Handler // per pattern, for routes with skipped root middlewares
+	unmatched    http.Handler
+	notFound     http.HandlerFunc // custom 404 handler, for disabled routes
+	errorHandler func(w http.ResponseWriter, r *http.Request, status int)
+	tracer       Tracer
+	versioning   VersioningOpts
+}
+
+// dispatcher returns the root middleware chains, composing them on first use.
+// Chains are cached until root middlewares or routes with skipped root middlewares change.
+func (b *Bundle) dispatcher() *dispatch {
+	if d := b.dispatch.Load(); d != nil {
+		return d
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.compose()
+}
+
+// compose builds the root middleware chains if not cached yet. Must be called with the tree lock held.
+func (b *Bundle) compose() *dispatch {
+	if d := b.dispatch.Load(); d != nil {
+		return d // composed by another request while we waited for the lock
+	}
+	notFound := b.notFound
+	d := &dispatch{
+		notFound:     notFound,
+		errorHandler: b.errorHandler,
+		tracer:       b.tracer,
+		versioning:   b.versioning,
+		chain:        b.wrapGlobal(b.mux, nil),
+		unmatched: b.wrapGlobal(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b.serveUnmatched(w, r, notFound)
+		}), nil),
+	}
+	for _, rt := range b.routes {
+		if len(rt.skip) == 0 {
+			continue
+		}
+		if d.chains == nil {
+			d.chains = make(map[string]http.Handler)
 		}
-		// let the mux handle the request normally (this sets path parameters)
+		d.chains[rt.pattern] = b.wrapGlobal(b.mux, rt.skip)
+	}
+	b.dispatch.Store(d)
+	return d
+}
+
+// serveUnmatched lets the mux handle a request without a matching route,
+// but intercepts 404s to use the custom handler if provided.
+func (b *Bundle) serveUnmatched(w http.ResponseWriter, r *http.Request, notFound http.HandlerFunc) {
+	if isPreflight(r) && b.preflight(w, r) {
+		return // preflight for a route with CORS enabled, see CORS
+	}
+	if notFound == nil {
 		b.mux.ServeHTTP(w, r)
-	})
+		return
+	}
+
+	// no route matched, need to check if it's a true 404 or a 405.
+	// if mux wants to return 405 (Method Not Allowed), the interceptor passes its response through
+	// to preserve the proper 405 response and Allow header, otherwise the response is discarded.
+	interceptor := &notFoundInterceptor{ResponseWriter: w}
+	b.mux.ServeHTTP(interceptor, r)
+	if interceptor.passed {
+		return
+	}
+
+	// it's a true 404, use custom handler
+	notFound.ServeHTTP(w, r)
+}
 
-	// apply root (global) middlewares around the mux handler and serve the request.
-	root.wrapGlobal(muxHandler).ServeHTTP(w, r)
+// Freeze finalizes the router. It validates routes against policies added with Require and
+// precomputes the middleware chains. After a successful Freeze the router is immutable: any further
+// Use, Handle, Mount, Group, With or NotFoundHandler call on any bundle of the tree panics.
+// If validation fails, the error is returned and the router is left unfrozen.
+// Calling Freeze on an already frozen router is a no-op.
+func (b *Bundle) Freeze() error {
+	root := b.rootBundle()
+	root.mu.Lock()
+	defer root.mu.Unlock()
+	if root.frozen {
+		return nil
+	}
+	if err := root.validate(); err != nil {
+		return err
+	}
+	root.compose()
+	root.frozen = true
+	return nil
 }
 
 // Group creates a new group with the same middleware stack as the original on top of the existing bundle.
 func (b *Bundle) Group() *Bundle {
+	mu := b.treeLock()
+	mu.Lock()
+	defer mu.Unlock()
 	return b.clone() // copy the middlewares to avoid modifying the original
 }
 
 // Mount creates a new group with a specified base path on top of the existing bundle.
 func (b *Bundle) Mount(basePath string) *Bundle {
+	mu := b.treeLock()
+	mu.Lock()
+	defer mu.Unlock()
 	g := b.clone() // copy the middlewares to avoid modifying the original
 	g.basePath += basePath
 	return g
@@ -105,42 +247,104 @@ func (b *Bundle) Mount(basePath string) *Bundle {
 // route pattern via r.Pattern, but execute before path parameters are parsed.
 // Therefore, r.PathValue() will return empty strings in root middlewares.
 // Middlewares on mounted groups execute after routing and have full access to path values.
-func (b *Bundle) Use(middleware func(http.Handler) http.Handler, more ...func(http.Handler) http.Handler) {
+func (b *Bundle) Use(mw func(http.Handler) http.Handler, more ...func(http.Handler) http.Handler) {
+	b.use(middlewares(mw, more))
+}
+
+// use adds the middleware entries to the Group, see Use.
+func (b *Bundle) use(mws []*middleware) {
+	mu := b.treeLock()
+	mu.Lock()
+	defer mu.Unlock()
+	b.checkFrozen()
 	// disallow adding middlewares after any routes have been registered on this bundle.
 	if b.routesLocked {
 		panic("routegroup: Use called after routes were registered on this bundle; add middlewares before registering routes or use Group/With for scoped middleware")
 	}
-	b.middlewares = append(b.middlewares, middleware)
-	b.middlewares = append(b.middlewares, more...)
+	b.middlewares = append(b.middlewares, mws...)
+	if b.root == nil {
+		b.dispatch.Store(nil) // root middlewares changed, cached chains are stale
+	}
 }
 
 // With adds new middleware(s) to the Group and returns a new Group with the updated middleware stack.
 // The With method is similar to Use, but instead of modifying the current Group,
 // it returns a new Group instance with the added middleware(s).
 // This allows for creating chain of middleware without affecting the original Group.
-func (b *Bundle) With(middleware func(http.Handler) http.Handler, more ...func(http.Handler) http.Handler) *Bundle {
-	newMiddlewares := make([]func(http.Handler) http.Handler, len(b.middlewares), len(b.middlewares)+len(more)+1)
-	copy(newMiddlewares, b.middlewares)
-	newMiddlewares = append(newMiddlewares, middleware)
-	newMiddlewares = append(newMiddlewares, more...)
-	// preserve root pointer and rootCount
-	nb := &Bundle{mux: b.mux, basePath: b.basePath, middlewares: newMiddlewares, root: b.root, rootCount: b.rootCount}
-	if nb.root == nil {
-		// b is the root, so all b's middlewares are root middlewares
-		nb.root = b
-		nb.rootCount = len(b.middlewares)
+func (b *Bundle) With(mw func(http.Handler) http.Handler, more ...func(http.Handler) http.Handler) *Bundle {
+	return b.with(middlewares(mw, more))
+}
+
+// with returns a new Group with the middleware entries added, see With.
+func (b *Bundle) with(mws []*middleware) *Bundle {
+	mu := b.treeLock()
+	mu.Lock()
+	defer mu.Unlock()
+	nb := b.clone()
+	nb.middlewares = append(nb.middlewares, mws...)
+	return nb
+}
+
+// Without returns a new Group that excludes inherited middlewares with the given names, see Named.
+// Both root-level and group-level middlewares are excluded for routes registered on the new Group,
+// while middlewares added to it afterwards are applied as usual. The original Group is not affected.
+func (b *Bundle) Without(name string, more ...string) *Bundle {
+	excluded := map[string]bool{name: true}
+	for _, n := range more {
+		excluded[n] = true
 	}
+
+	mu := b.treeLock()
+	mu.Lock()
+	defer mu.Unlock()
+	nb := b.clone()
+	kept := make([]*middleware, 0, len(nb.middlewares))
+	rootCount := nb.rootCount
+	for i, mw := range nb.middlewares {
+		if !excluded[mw.name()] {
+			kept = append(kept, mw)
+			continue
+		}
+		if i < nb.rootCount {
+			// root middlewares are applied globally in ServeHTTP, record them to be skipped for our routes
+			nb.skip = append(nb.skip, mw)
+			rootCount--
+		}
+	}
+	nb.middlewares, nb.rootCount = kept, rootCount
 	return nb
 }
 
+// UseIf adds middleware(s) to the Group that run only for requests matching the predicate.
+// Requests for which pred returns false skip the middleware and go straight to the next handler.
+// The predicate is evaluated per request for each middleware. Like with Use, predicates of
+// root-level middlewares can read the matched route pattern via r.Pattern.
+func (b *Bundle) UseIf(pred func(*http.Request) bool, mw func(http.Handler) http.Handler,
+	more ...func(http.Handler) http.Handler) {
+	b.use(conditionalAll(pred, middlewares(mw, more)))
+}
+
+// WithIf is like With, but the added middleware(s) run only for requests matching the predicate.
+// It returns a new Group, leaving the original Group unchanged.
+func (b *Bundle) WithIf(pred func(*http.Request) bool, mw func(http.Handler) http.Handler,
+	more ...func(http.Handler) http.Handler) *Bundle {
+	return b.with(conditionalAll(pred, middlewares(mw, more)))
+}
+
 // Handle adds a new route to the Group's mux, applying all middlewares to the handler.
 func (b *Bundle) Handle(pattern string, handler http.Handler) {
+	mu := b.treeLock()
+	mu.Lock()
+	defer mu.Unlock()
 	b.lockRoot() // lock root on first route registration
 
 	// for file server paths (ending with /), preserve the pattern as-is
 	if strings.HasSuffix(pattern, "/") {
-		fullPath := b.basePath + pattern
-		b.mux.Handle(fullPath, b.wrapMiddleware(handler))
+		if b.version != "" {
+			b.handleVersion(b.versionPath()+pattern, b.basePath+pattern, handler)
+			return
+		}
+		b.handle(b.basePath+pattern, handler)
 		return
 	}
 	b.register(pattern, handler.ServeHTTP)
@@ -148,6 +352,9 @@ func (b *Bundle) Handle(pattern string, handler http.Handler) {
 
 // HandleFiles is a helper to serve static files from a directory
 func (b *Bundle) HandleFiles(pattern string, root http.FileSystem) {
+	mu := b.treeLock()
+	mu.Lock()
+	defer mu.Unlock()
 	b.lockRoot() // lock root on first route registration
 
 	// normalize pattern to always have trailing slash
@@ -160,18 +367,21 @@ func (b *Bundle) HandleFiles(pattern string, root http.FileSystem) {
 
 	if pattern == "/" && b.basePath == "" {
 		// root case - serve directly without stripping
-		b.mux.Handle("/", b.wrapMiddleware(http.FileServer(root)))
+		b.handle("/", http.FileServer(root))
 		return
 	}
 
 	// for both mounted groups and prefixed paths, strip the fullPath
 	handler := http.StripPrefix(strings.TrimSuffix(fullPath, "/"), http.FileServer(root))
-	b.mux.Handle(fullPath, b.wrapMiddleware(handler))
+	b.handle(fullPath, handler)
 }
 
 // HandleFunc registers the handler function for the given pattern to the Group's mux.
 // The handler is wrapped with the Group's middlewares.
 func (b *Bundle) HandleFunc(pattern string, handler http.HandlerFunc) {
+	mu := b.treeLock()
+	mu.Lock()
+	defer mu.Unlock()
 	b.register(pattern, handler)
 }
 
@@ -190,12 +400,47 @@ func (b *Bundle) DisableNotFoundHandler() {}
 // Note: This handler is only used for true 404s. Requests to valid paths with
 // incorrect HTTP methods will still return 405 Method Not Allowed with Allow header.
 func (b *Bundle) NotFoundHandler(handler http.HandlerFunc) {
+	mu := b.treeLock()
+	mu.Lock()
+	defer mu.Unlock()
+	b.checkFrozen()
 	// always set on the root bundle so custom 404 works regardless of which bundle serves.
-	if b.root != nil {
-		b.root.notFound = handler
+	root := b.rootBundle()
+	root.notFound = handler
+	root.dispatch.Store(nil) // the 404 handler is part of the composed chains
+}
+
+// ErrorHandler sets a custom handler rendering error responses produced by the package itself, e.g. for
+// routes disabled with a status other than 404, rejected CORS preflights and CSRF failures. The handler
+// gets the status to respond with. Without it, errors are rendered with http.Error and the status text.
+// Unmatched routes are still handled by NotFoundHandler.
+func (b *Bundle) ErrorHandler(handler func(w http.ResponseWriter, r *http.Request, status int)) {
+	mu := b.treeLock()
+	mu.Lock()
+	defer mu.Unlock()
+	b.checkFrozen()
+	root := b.rootBundle()
+	root.errorHandler = handler
+	root.dispatch.Store(nil) // the error handler is part of the composed dispatch
+}
+
+// renderError responds with the error status using the custom error handler if set.
+func (b *Bundle) renderError(w http.ResponseWriter, r *http.Request, status int) {
+	if h := b.rootBundle().dispatcher().errorHandler; h != nil {
+		h(w, r, status)
+		return
+	}
+	http.Error(w, http.StatusText(status), status)
+}
+
+// renderStatus responds with the status using the custom 404 handler for 404 if set,
+// and renderError otherwise.
+func (b *Bundle) renderStatus(w http.ResponseWriter, r *http.Request, status int) {
+	if notFound := b.rootBundle().dispatcher().notFound; status == http.StatusNotFound && notFound != nil {
+		notFound.ServeHTTP(w, r)
 		return
 	}
-	b.notFound = handler
+	b.renderError(w, r, status)
 }
 
 // matches non-space characters, spaces, then anything, i.e. "GET /path/to/resource"
@@ -203,27 +448,41 @@ var reGo122 = regexp.MustCompile(`^(\S+)\s+(.+)$`)
 
 func (b *Bundle) register(pattern string, handler http.HandlerFunc) {
 	b.lockRoot() // lock root on first route registration
+	if b.version != "" {
+		b.handleVersion(joinPattern(b.versionPath(), pattern), b.fullPattern(pattern), handler)
+		return
+	}
+	b.handle(b.fullPattern(pattern), handler)
+}
+
+// fullPattern returns the pattern prefixed with the bundle's base path.
+func (b *Bundle) fullPattern(pattern string) string {
+	return joinPattern(b.basePath, pattern)
+}
+
+// joinPattern returns the pattern prefixed with the base path.
+func joinPattern(basePath, pattern string) string {
 	matches := reGo122.FindStringSubmatch(pattern)
 	var path, method string
 	if len(matches) > 2 { // path in the form "GET /path/to/resource"
 		method = matches[1]
 		path = matches[2]
-		pattern = method + " " + b.basePath + path
+		pattern = method + " " + basePath + path
 	} else { // path is just "/path/to/resource"
 		path = pattern
-		pattern = b.basePath + pattern
+		pattern = basePath + pattern
 		// method is not set intentionally here, the request pattern had no method part
 	}
 	// if the pattern is the root path on / change it to /{$}
 	// this keeps handling the root request without becoming a catch-all
 	if pattern == "/" || path == "/" {
 		if method != "" { // preserve the method part if it was set
-			pattern = method + " " + b.basePath + "/{$}"
+			pattern = method + " " + basePath + "/{$}"
 		} else {
-			pattern = b.basePath + "/{$}" // no method part, just the path
+			pattern = basePath + "/{$}" // no method part, just the path
 		}
 	}
-	b.mux.HandleFunc(pattern, b.wrapMiddleware(handler).ServeHTTP)
+	return pattern
 }
 
 // Route allows for configuring the Group inside the configureFn function.
@@ -235,6 +494,9 @@ func (b *Bundle) Route(configureFn func(*Bundle)) {
 		child := b.Group()
 		configureFn(child)
 		// if child registered routes, lock root too to prevent Use() after routes
+		mu := b.treeLock()
+		mu.Lock()
+		defer mu.Unlock()
 		if child.routesLocked {
 			b.routesLocked = true
 		}
@@ -247,6 +509,9 @@ func (b *Bundle) Route(configureFn func(*Bundle)) {
 // This avoids the 301 redirect that would occur with a "/" pattern.
 // Method parameter can be empty to register for all HTTP methods.
 func (b *Bundle) HandleRoot(method string, handler http.Handler) {
+	mu := b.treeLock()
+	mu.Lock()
+	defer mu.Unlock()
 	b.lockRoot() // lock root on first route registration
 
 	// for empty base path, use "/" to match the root
@@ -260,11 +525,14 @@ func (b *Bundle) HandleRoot(method string, handler http.Handler) {
 		pattern = method + " " + pattern
 	}
 
-	b.mux.Handle(pattern, b.wrapMiddleware(handler))
+	b.handle(pattern, handler)
 }
 
 // HandleRootFunc is like HandleRoot but takes a handler function.
 func (b *Bundle) HandleRootFunc(method string, handler http.HandlerFunc) {
+	mu := b.treeLock()
+	mu.Lock()
+	defer mu.Unlock()
 	b.lockRoot() // lock root on first route registration
 
 	// for empty base path, use "/" to match the root
@@ -278,33 +546,63 @@ func (b *Bundle) HandleRootFunc(method string, handler http.HandlerFunc) {
 		pattern = method + " " + pattern
 	}
 
-	b.mux.HandleFunc(pattern, b.wrapMiddleware(handler).ServeHTTP)
+	b.handle(pattern, handler)
+}
+
+// handle registers the handler wrapped with the bundle's middlewares for the full pattern
+// and records the route on the root bundle. A route with selectors, or one sharing the pattern with
+// such a route, is added as a variant of the route already registered with the mux for the pattern.
+// It returns the registered route.
+func (b *Bundle) handle(pattern string, handler http.Handler, selectors ...selector) *route {
+	root := b.rootBundle()
+	selectors = append(selectors, b.contentSelectors()...)
+	rt := &route{pattern: pattern, wildcards: patternWildcards(pattern), middlewares: b.groupMiddlewares(),
+		skip: b.skip, root: root, cors: b.cors, consumes: b.consumes, produces: b.produces,
+		selectors: selectors}
+	rt.handler.Store(&handlerRef{b.wrapMiddleware(handler)})
+	if prev, ok := root.index[pattern]; ok && (len(selectors) > 0 || len(prev.selectors) > 0 || prev.variants.Load() != nil) {
+		prev.addVariant(rt)
+		b.addRoute(rt)
+		return rt
+	}
+	b.mux.Handle(pattern, rt)
+	b.addRoute(rt)
+	return rt
 }
 
 // wrapMiddleware applies the registered middlewares to a handler.
 func (b *Bundle) wrapMiddleware(handler http.Handler) http.Handler {
-	// root bundle: don't apply middlewares here, they're applied globally in ServeHTTP
+	// root bundle has no group middlewares, they're applied globally in ServeHTTP
+	mws := b.groupMiddlewares()
+	for i := len(mws) - 1; i >= 0; i-- {
+		handler = mws[i].fn(handler)
+	}
+	return handler
+}
+
+// groupMiddlewares returns middlewares applied at registration time. For the root bundle it's empty,
+// as root middlewares are applied globally in ServeHTTP.
+func (b *Bundle) groupMiddlewares() []*middleware {
 	if b.root == nil {
-		return handler
+		return nil
 	}
 
-	// child bundle: apply only middlewares added after mounting (exclude inherited root middlewares)
+	// child bundle: only middlewares added after mounting (exclude inherited root middlewares)
 	start := b.rootCount
 	if start > len(b.middlewares) {
 		start = len(b.middlewares) // safety: ensure start doesn't exceed bounds
 	}
-
-	for i := len(b.middlewares) - 1; i >= start; i-- {
-		handler = b.middlewares[i](handler)
-	}
-	return handler
+	return b.middlewares[start:len(b.middlewares):len(b.middlewares)]
 }
 
 func (b *Bundle) clone() *Bundle {
-	middlewares := make([]func(http.Handler) http.Handler, len(b.middlewares))
+	b.checkFrozen()
+	middlewares := make([]*middleware, len(b.middlewares))
 	copy(middlewares, b.middlewares)
-	// preserve root pointer and rootCount
-	nb := &Bundle{mux: b.mux, basePath: b.basePath, middlewares: middlewares, root: b.root, rootCount: b.rootCount}
+	// preserve root pointer, rootCount and skipped root middlewares
+	nb := &Bundle{mux: b.mux, basePath: b.basePath, middlewares: middlewares, root: b.root, rootCount: b.rootCount,
+		skip: b.skip[:len(b.skip):len(b.skip)], cors: b.cors, version: b.version, versionBase: b.versionBase,
+		consumes: b.consumes, produces: b.produces}
 	if nb.root == nil {
 		// b is the root, so all b's middlewares are root middlewares
 		nb.root = b
@@ -321,36 +619,112 @@ func Wrap(handler http.Handler, mw1 func(http.Handler) http.Handler, mws ...func
 	return mw1(handler) // apply the first middleware
 }
 
-// wrapGlobal applies only the root bundle's middlewares to the provided handler.
-func (b *Bundle) wrapGlobal(handler http.Handler) http.Handler {
-	// resolve root bundle
-	root := b
-	if b.root != nil {
-		root = b.root
+// conditional wraps the middleware so it is applied only when pred returns true for the request.
+func conditional(pred func(*http.Request) bool, mw func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		wrapped := mw(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if pred(r) {
+				wrapped.ServeHTTP(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// conditionalAll makes each middleware entry conditional, keeping the wrapped middleware
+// to identify the entry by.
+func conditionalAll(pred func(*http.Request) bool, mws []*middleware) []*middleware {
+	for _, m := range mws {
+		m.target, m.fn, m.conditional = m.fn, conditional(pred, m.fn), true
 	}
-	for i := len(root.middlewares) - 1; i >= 0; i-- {
-		handler = root.middlewares[i](handler)
+	return mws
+}
+
+// wrapGlobal applies only the root bundle's middlewares to the provided handler,
+// leaving out the skipped ones.
+func (b *Bundle) wrapGlobal(handler http.Handler, skip []*middleware) http.Handler {
+	mws := b.globalMiddlewares(skip)
+	for i := len(mws) - 1; i >= 0; i-- {
+		handler = mws[i].fn(handler)
 	}
 	return handler
 }
 
+// treeLock returns the lock guarding changes of all bundles in the tree.
+// All exported methods changing or reading the tree state take it, internal helpers expect it held.
+func (b *Bundle) treeLock() *sync.Mutex {
+	return &b.rootBundle().mu
+}
+
+// rootBundle returns the root bundle of the tree, the bundle itself for the root.
+func (b *Bundle) rootBundle() *Bundle {
+	if b.root != nil {
+		return b.root
+	}
+	return b
+}
+
 // lockRoot marks this bundle as having registered routes.
-func (b *Bundle) lockRoot() { b.routesLocked = true }
+func (b *Bundle) lockRoot() {
+	b.checkFrozen()
+	b.routesLocked = true
+}
+
+// checkFrozen panics if the router has been finalized with Freeze.
+func (b *Bundle) checkFrozen() {
+	if b.rootBundle().frozen {
+		panic("routegroup: router is frozen, no changes allowed after Freeze")
+	}
+}
 
-// statusRecorder is a minimal ResponseWriter that only records the status code.
-// Used to probe what status the mux would return without actually writing a response.
-type statusRecorder struct {
-	status int
+// notFoundInterceptor is a ResponseWriter passing through only 405 (Method Not Allowed) responses of the mux.
+// Any other response, i.e. 404, is discarded to be replaced by the custom handler. Header changes are made
+// on a copy of the headers and applied to the underlying writer only if the response is passed through.
+type notFoundInterceptor struct {
+	http.ResponseWriter
+	header      http.Header
+	wroteHeader bool
+	passed      bool
 }
 
-func (r *statusRecorder) Header() http.Header {
-	return make(http.Header)
+func (i *notFoundInterceptor) Header() http.Header {
+	if i.header == nil {
+		i.header = i.ResponseWriter.Header().Clone()
+	}
+	return i.header
 }
 
-func (r *statusRecorder) Write([]byte) (int, error) {
-	return 0, nil
+func (i *notFoundInterceptor) Write(b []byte) (int, error) {
+	if !i.wroteHeader {
+		i.WriteHeader(http.StatusOK)
+	}
+	if !i.passed {
+		return len(b), nil
+	}
+	return i.ResponseWriter.Write(b)
 }
 
-func (r *statusRecorder) WriteHeader(status int) {
-	r.status = status
+func (i *notFoundInterceptor) WriteHeader(status int) {
+	if i.wroteHeader {
+		return
+	}
+	i.wroteHeader = true
+	if status != http.StatusMethodNotAllowed {
+		return
+	}
+	i.passed = true
+	if i.header != nil {
+		h := i.ResponseWriter.Header()
+		for k := range h {
+			if _, ok := i.header[k]; !ok {
+				delete(h, k)
+			}
+		}
+		for k, v := range i.header {
+			h[k] = v
+		}
+	}
+	i.ResponseWriter.WriteHeader(status)
 }
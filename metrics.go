@@ -0,0 +1,165 @@
+package routegroup
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultMetricsBuckets are latency histogram buckets in seconds used by NewMetrics if none given.
+var DefaultMetricsBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Metrics collects per-route request metrics labeled by the matched route pattern rather than the raw
+// URL path, which keeps the number of label values bounded. It records request counts by status class,
+// in-flight requests and latency histograms for each pattern, plus counters for unmatched requests
+// answered with 404 and 405. Metrics is an http.Handler exposing them in Prometheus text format.
+//
+//	m := routegroup.NewMetrics()
+//	router.Use(m.Middleware)
+//	router.Handle("GET /metrics", m)
+type Metrics struct {
+	// Middleware records metrics of requests. It is meant to be a root middleware, so unmatched
+	// requests are counted too; for group middlewares only requests of the group's routes are seen.
+	// The middleware is named "metrics".
+	Middleware func(http.Handler) http.Handler
+
+	buckets  []float64
+	patterns sync.Map // pattern -> *patternMetrics
+
+	notFound         atomic.Uint64
+	methodNotAllowed atomic.Uint64
+}
+
+type patternMetrics struct {
+	classes  [5]atomic.Uint64 // 1xx..5xx
+	inFlight atomic.Int64
+	buckets  []atomic.Uint64 // non-cumulative, last one is +Inf
+	count    atomic.Uint64
+	sumNanos atomic.Int64
+}
+
+// NewMetrics makes Metrics with the given latency buckets in seconds, DefaultMetricsBuckets if none given.
+// Metrics must be made with it, as it sets the Middleware.
+func NewMetrics(buckets ...float64) *Metrics {
+	if len(buckets) == 0 {
+		buckets = DefaultMetricsBuckets
+	}
+	b := make([]float64, len(buckets))
+	copy(b, buckets)
+	sort.Float64s(b)
+	m := &Metrics{buckets: b}
+	m.Middleware = Named("metrics", m.middleware)
+	return m
+}
+
+// middleware records metrics of requests, see Metrics.Middleware.
+func (m *Metrics) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Pattern == "" {
+			sw := NewResponseWriter(w)
+			next.ServeHTTP(sw, r)
+			switch sw.Status() {
+			case http.StatusNotFound:
+				m.notFound.Add(1)
+			case http.StatusMethodNotAllowed:
+				m.methodNotAllowed.Add(1)
+			}
+			return
+		}
+
+		pm := m.pattern(r.Pattern)
+		pm.inFlight.Add(1)
+		defer pm.inFlight.Add(-1)
+		sw := NewResponseWriter(w)
+		start := time.Now()
+		next.ServeHTTP(sw, r)
+		pm.observe(sw.Status(), time.Since(start), m.buckets)
+	})
+}
+
+func (m *Metrics) pattern(pattern string) *patternMetrics {
+	if pm, ok := m.patterns.Load(pattern); ok {
+		return pm.(*patternMetrics)
+	}
+	pm, _ := m.patterns.LoadOrStore(pattern, &patternMetrics{buckets: make([]atomic.Uint64, len(m.buckets)+1)})
+	return pm.(*patternMetrics)
+}
+
+func (pm *patternMetrics) observe(status int, d time.Duration, buckets []float64) {
+	if class := status/100 - 1; class >= 0 && class < len(pm.classes) {
+		pm.classes[class].Add(1)
+	}
+	idx := sort.SearchFloat64s(buckets, d.Seconds()) // first bucket with le >= d
+	pm.buckets[idx].Add(1)
+	pm.count.Add(1)
+	pm.sumNanos.Add(int64(d))
+}
+
+// ServeHTTP writes the metrics in Prometheus text exposition format.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	bw := bufio.NewWriter(w)
+	m.write(bw)
+	_ = bw.Flush()
+}
+
+func (m *Metrics) write(w *bufio.Writer) {
+	type entry struct {
+		label string
+		pm    *patternMetrics
+	}
+	var entries []entry
+	m.patterns.Range(func(k, v any) bool {
+		entries = append(entries, entry{label: `pattern="` + escapeLabel(k.(string)) + `"`, pm: v.(*patternMetrics)})
+		return true
+	})
+	sort.Slice(entries, func(i, j int) bool { return entries[i].label < entries[j].label })
+
+	header := func(name, typ, help string) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+	}
+
+	header("http_requests_total", "counter", "Number of HTTP requests by route pattern and status class.")
+	for _, e := range entries {
+		for i := range e.pm.classes {
+			if n := e.pm.classes[i].Load(); n > 0 {
+				fmt.Fprintf(w, "http_requests_total{%s,code=\"%dxx\"} %d\n", e.label, i+1, n)
+			}
+		}
+	}
+
+	header("http_requests_in_flight", "gauge", "Number of HTTP requests being served by route pattern.")
+	for _, e := range entries {
+		fmt.Fprintf(w, "http_requests_in_flight{%s} %d\n", e.label, e.pm.inFlight.Load())
+	}
+
+	header("http_request_duration_seconds", "histogram", "HTTP request latency by route pattern.")
+	for _, e := range entries {
+		var cumulative uint64
+		for i, le := range m.buckets {
+			cumulative += e.pm.buckets[i].Load()
+			fmt.Fprintf(w, "http_request_duration_seconds_bucket{%s,le=\"%s\"} %d\n",
+				e.label, strconv.FormatFloat(le, 'g', -1, 64), cumulative)
+		}
+		count := e.pm.count.Load()
+		fmt.Fprintf(w, "http_request_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", e.label, count)
+		fmt.Fprintf(w, "http_request_duration_seconds_sum{%s} %s\n", e.label,
+			strconv.FormatFloat(time.Duration(e.pm.sumNanos.Load()).Seconds(), 'g', -1, 64))
+		fmt.Fprintf(w, "http_request_duration_seconds_count{%s} %d\n", e.label, count)
+	}
+
+	header("http_not_found_total", "counter", "Number of requests not matching any route.")
+	fmt.Fprintf(w, "http_not_found_total %d\n", m.notFound.Load())
+	header("http_method_not_allowed_total", "counter", "Number of requests matching a route path but not its method.")
+	fmt.Fprintf(w, "http_method_not_allowed_total %d\n", m.methodNotAllowed.Load())
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
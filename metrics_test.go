@@ -0,0 +1,79 @@
+package routegroup_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-pkgz/routegroup"
+)
+
+func TestMetrics(t *testing.T) {
+	m := routegroup.NewMetrics(0.1, 1)
+	rtr := routegroup.New(http.NewServeMux())
+	rtr.Use(m.Middleware)
+	rtr.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") == "bad" {
+			http.Error(w, "bad", http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	})
+	rtr.Mount("/api").HandleFunc(`POST /say/"hi"`, func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusEarlyHints)
+		w.WriteHeader(http.StatusCreated)
+	})
+	rtr.Handle("GET /metrics", m)
+
+	for _, req := range []struct{ method, path string }{
+		{"GET", "/users/1"}, {"GET", "/users/2"}, {"GET", "/users/bad"},
+		{"POST", `/api/say/"hi"`}, {"GET", "/nope"}, {"DELETE", "/users/1"},
+	} {
+		rtr.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(req.method, req.path, http.NoBody))
+	}
+
+	rec := httptest.NewRecorder()
+	rtr.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", http.NoBody))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("unexpected content type %q", ct)
+	}
+	body, _ := io.ReadAll(rec.Body)
+	out := string(body)
+
+	for _, want := range []string{
+		`http_requests_total{pattern="GET /users/{id}",code="2xx"} 2`,
+		`http_requests_total{pattern="GET /users/{id}",code="4xx"} 1`,
+		`http_requests_total{pattern="POST /api/say/\"hi\"",code="2xx"} 1`,
+		`http_requests_in_flight{pattern="GET /users/{id}"} 0`,
+		`http_requests_in_flight{pattern="GET /metrics"} 1`,
+		`http_request_duration_seconds_bucket{pattern="GET /users/{id}",le="0.1"} 3`,
+		`http_request_duration_seconds_bucket{pattern="GET /users/{id}",le="1"} 3`,
+		`http_request_duration_seconds_bucket{pattern="GET /users/{id}",le="+Inf"} 3`,
+		`http_request_duration_seconds_count{pattern="GET /users/{id}"} 3`,
+		"# TYPE http_request_duration_seconds histogram",
+		"http_not_found_total 1",
+		"http_method_not_allowed_total 1",
+	} {
+		if !strings.Contains(out, want+"\n") {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "/users/1") || strings.Contains(out, "/nope") {
+		t.Errorf("raw paths leaked into labels:\n%s", out)
+	}
+
+	// the middleware is named, so it's reported by its name and can be excluded
+	rtr.Without("metrics").HandleFunc("GET /health", func(http.ResponseWriter, *http.Request) {})
+	for _, ri := range rtr.Routes() {
+		want := []string{"metrics"}
+		if ri.Pattern == "GET /health" {
+			want = nil
+		}
+		if !reflect.DeepEqual(ri.Middlewares, want) {
+			t.Errorf("%s: unexpected middlewares %v", ri.Pattern, ri.Middlewares)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package routegroup
+
+import (
+	"net/http"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// middleware is an entry of the bundle's middlewares stack.
+// Entries are shared by pointer between derived bundles, so the same middleware
+// can be identified across the tree.
+type middleware struct {
+	fn          func(http.Handler) http.Handler
+	rate        *Rate        // limit of the rate limiting middleware, reported by Routes
+	deprecation *Deprecation // deprecation of the route, reported by Routes
+
+	// target is the middleware identifying the entry if fn wraps it, e.g. the one applied
+	// conditionally with UseIf, nil if fn is the middleware itself.
+	target      func(http.Handler) http.Handler
+	conditional bool // applied only to requests matching the predicate of UseIf or WithIf
+
+	probeOnce sync.Once
+	probed    nameProbe
+}
+
+// middlewares returns the entries for the middlewares.
+func middlewares(mw func(http.Handler) http.Handler, more []func(http.Handler) http.Handler) []*middleware {
+	res := make([]*middleware, 0, len(more)+1)
+	res = append(res, &middleware{fn: mw})
+	for _, m := range more {
+		res = append(res, &middleware{fn: m})
+	}
+	return res
+}
+
+// name returns the name given to the middleware with Named, or an empty string.
+func (m *middleware) name() string {
+	return m.probe().name
+}
+
+// label returns the name of the middleware for introspection. Unnamed middlewares
+// are labeled with the name of their function, e.g. "main.authMiddleware". Conditional middlewares
+// get the " (conditional)" suffix, e.g. "auth (conditional)", as they may not run for the route's requests.
+func (m *middleware) label() string {
+	p := m.probe()
+	label := p.name
+	if label == "" {
+		name := runtime.FuncForPC(reflect.ValueOf(p.fn).Pointer()).Name()
+		label = name[strings.LastIndex(name, "/")+1:]
+	}
+	if m.conditional {
+		label += " (conditional)"
+	}
+	return label
+}
+
+// probe discovers the name of the middleware. Middlewares created with Named are given the placeholder
+// handler to report their name, without calling the wrapped middleware. Other middlewares are never called,
+// as creating them may have side effects, so they have no name.
+// The result is resolved on first use and cached.
+func (m *middleware) probe() *nameProbe {
+	m.probeOnce.Do(func() {
+		fn := m.fn
+		if m.target != nil {
+			fn = m.target
+		}
+		m.probed.fn = fn
+		if reflect.ValueOf(fn).Pointer() == namedPC {
+			fn(&m.probed)
+		}
+	})
+	return &m.probed
+}
+
+// Named attaches a name to the middleware. The name identifies the middleware in the bundle's
+// stack, for example to exclude it from a derived group with Without, and is reported in the
+// middleware chain of routes returned by Routes. Naming has no effect on how the middleware is applied.
+func Named(name string, mw func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return (&namedMiddleware{name: name, mw: mw}).wrap
+}
+
+// namedMiddleware is a middleware created with Named.
+type namedMiddleware struct {
+	name string
+	mw   func(http.Handler) http.Handler
+}
+
+// namedPC identifies middlewares created with Named, which share the code of the method value.
+var namedPC = reflect.ValueOf((&namedMiddleware{}).wrap).Pointer()
+
+// wrap applies the named middleware, or reports its name to the placeholder handler.
+func (n *namedMiddleware) wrap(next http.Handler) http.Handler {
+	if p, ok := next.(*nameProbe); ok {
+		p.name = n.name
+		return p
+	}
+	return n.mw(next)
+}
+
+// nameProbe is a placeholder handler passed to middlewares created with Named to discover their names.
+type nameProbe struct {
+	name string
+	fn   func(http.Handler) http.Handler // middleware function, used for labeling
+}
+
+func (p *nameProbe) ServeHTTP(http.ResponseWriter, *http.Request) {}
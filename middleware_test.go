@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"sync"
 	"testing"
 
@@ -1271,3 +1272,273 @@ func TestRequestIsolation(t *testing.T) {
 		}
 	})
 }
+
+func TestUseIf(t *testing.T) {
+	headerMw := func(name string) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set(name, "true")
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	unsafeMethod := func(r *http.Request) bool {
+		return r.Method != http.MethodGet && r.Method != http.MethodHead
+	}
+
+	t.Run("root middleware applied only for matching requests", func(t *testing.T) {
+		rtr := routegroup.New(http.NewServeMux())
+		rtr.UseIf(unsafeMethod, headerMw("X-Csrf"), headerMw("X-More"))
+		rtr.HandleFunc("/items", func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) })
+
+		rec := httptest.NewRecorder()
+		rtr.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/items", http.NoBody))
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected status 200, got %d", rec.Code)
+		}
+		if h := rec.Header().Get("X-Csrf"); h != "" {
+			t.Errorf("X-Csrf should not be set for GET, got %q", h)
+		}
+		if h := rec.Header().Get("X-More"); h != "" {
+			t.Errorf("X-More should not be set for GET, got %q", h)
+		}
+
+		rec = httptest.NewRecorder()
+		rtr.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/items", http.NoBody))
+		if h := rec.Header().Get("X-Csrf"); h != "true" {
+			t.Errorf("X-Csrf should be set for POST, got %q", h)
+		}
+		if h := rec.Header().Get("X-More"); h != "true" {
+			t.Errorf("X-More should be set for POST, got %q", h)
+		}
+	})
+
+	t.Run("root predicate can read pattern", func(t *testing.T) {
+		rtr := routegroup.New(http.NewServeMux())
+		rtr.UseIf(func(r *http.Request) bool { return r.Pattern != "GET /health" }, headerMw("X-Auth"))
+		rtr.HandleFunc("GET /health", func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) })
+		rtr.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) })
+
+		rec := httptest.NewRecorder()
+		rtr.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", http.NoBody))
+		if h := rec.Header().Get("X-Auth"); h != "" {
+			t.Errorf("X-Auth should not be set for health, got %q", h)
+		}
+
+		rec = httptest.NewRecorder()
+		rtr.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users/1", http.NoBody))
+		if h := rec.Header().Get("X-Auth"); h != "true" {
+			t.Errorf("X-Auth should be set for users, got %q", h)
+		}
+	})
+
+	t.Run("group middleware with header predicate", func(t *testing.T) {
+		rtr := routegroup.New(http.NewServeMux())
+		api := rtr.Mount("/api")
+		api.UseIf(func(r *http.Request) bool { return r.Header.Get("Content-Type") == "application/json" }, headerMw("X-JSON"))
+		api.HandleFunc("POST /data", func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) })
+
+		req := httptest.NewRequest(http.MethodPost, "/api/data", http.NoBody)
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		rtr.ServeHTTP(rec, req)
+		if h := rec.Header().Get("X-JSON"); h != "true" {
+			t.Errorf("X-JSON should be set for json request, got %q", h)
+		}
+
+		rec = httptest.NewRecorder()
+		rtr.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/data", http.NoBody))
+		if h := rec.Header().Get("X-JSON"); h != "" {
+			t.Errorf("X-JSON should not be set for non-json request, got %q", h)
+		}
+	})
+}
+
+func TestWithIf(t *testing.T) {
+	var called int
+	mw := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			called++
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	rtr := routegroup.New(http.NewServeMux())
+	rtr.WithIf(func(r *http.Request) bool { return r.Method == http.MethodDelete }, mw).
+		HandleFunc("/res/{id}", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(r.PathValue("id")))
+		})
+	rtr.HandleFunc("/other", func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) })
+
+	for _, method := range []string{http.MethodGet, http.MethodDelete, http.MethodPut, http.MethodDelete} {
+		rec := httptest.NewRecorder()
+		rtr.ServeHTTP(rec, httptest.NewRequest(method, "/res/42", http.NoBody))
+		if body := rec.Body.String(); body != "42" {
+			t.Errorf("%s: expected body 42, got %q", method, body)
+		}
+	}
+	if called != 2 {
+		t.Errorf("expected middleware to be called 2 times, got %d", called)
+	}
+
+	rec := httptest.NewRecorder()
+	rtr.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/other", http.NoBody))
+	if called != 2 {
+		t.Errorf("WithIf middleware should not affect the original group, called %d times", called)
+	}
+}
+
+func TestWithout(t *testing.T) {
+	trace := func(name string, calls *[]string) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				*calls = append(*calls, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	ok := func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) }
+
+	serve := func(t *testing.T, h http.Handler, calls *[]string, path string) []string {
+		t.Helper()
+		*calls = nil
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, http.NoBody))
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s: expected status 200, got %d", path, rec.Code)
+		}
+		return *calls
+	}
+
+	t.Run("exclude root middleware", func(t *testing.T) {
+		var calls []string
+		rtr := routegroup.New(http.NewServeMux())
+		rtr.Use(trace("log", &calls), routegroup.Named("auth", trace("auth", &calls)))
+
+		api := rtr.Mount("/api")
+		api.Use(trace("api", &calls))
+		api.HandleFunc("/private", ok)
+		public := api.Without("auth")
+		public.Use(trace("public", &calls))
+		public.HandleFunc("/public", ok)
+		rtr.HandleFunc("/root", ok)
+
+		if got := serve(t, rtr, &calls, "/api/private"); !reflect.DeepEqual(got, []string{"log", "auth", "api"}) {
+			t.Errorf("unexpected chain for private route: %v", got)
+		}
+		if got := serve(t, rtr, &calls, "/api/public"); !reflect.DeepEqual(got, []string{"log", "api", "public"}) {
+			t.Errorf("unexpected chain for public route: %v", got)
+		}
+		if got := serve(t, rtr, &calls, "/root"); !reflect.DeepEqual(got, []string{"log", "auth"}) {
+			t.Errorf("unexpected chain for root route: %v", got)
+		}
+	})
+
+	t.Run("exclude group middleware", func(t *testing.T) {
+		var calls []string
+		rtr := routegroup.New(http.NewServeMux())
+		rtr.Use(trace("log", &calls))
+		api := rtr.Mount("/api")
+		api.Use(routegroup.Named("auth", trace("auth", &calls)), routegroup.Named("audit", trace("audit", &calls)))
+		api.HandleFunc("/private", ok)
+		api.Without("auth", "audit").HandleFunc("/public", ok)
+		api.Without("audit").With(trace("with", &calls)).HandleFunc("/no-audit", ok)
+
+		if got := serve(t, rtr, &calls, "/api/private"); !reflect.DeepEqual(got, []string{"log", "auth", "audit"}) {
+			t.Errorf("unexpected chain for private route: %v", got)
+		}
+		if got := serve(t, rtr, &calls, "/api/public"); !reflect.DeepEqual(got, []string{"log"}) {
+			t.Errorf("unexpected chain for public route: %v", got)
+		}
+		if got := serve(t, rtr, &calls, "/api/no-audit"); !reflect.DeepEqual(got, []string{"log", "auth", "with"}) {
+			t.Errorf("unexpected chain for no-audit route: %v", got)
+		}
+	})
+
+	t.Run("root middleware added after Without still applies", func(t *testing.T) {
+		var calls []string
+		rtr := routegroup.New(http.NewServeMux())
+		rtr.Use(routegroup.Named("auth", trace("auth", &calls)))
+		rtr.Without("auth").HandleFunc("/public", ok)
+		rtr.Use(routegroup.Named("auth", trace("late", &calls)))
+
+		if got := serve(t, rtr, &calls, "/public"); !reflect.DeepEqual(got, []string{"late"}) {
+			t.Errorf("unexpected chain for public route: %v", got)
+		}
+	})
+
+	t.Run("unknown name and unnamed middlewares kept", func(t *testing.T) {
+		var calls []string
+		rtr := routegroup.New(http.NewServeMux())
+		rtr.Use(trace("log", &calls))
+		grp := rtr.Group()
+		grp.Use(trace("grp", &calls))
+		grp.Without("nope").HandleFunc("/path", ok)
+
+		if got := serve(t, rtr, &calls, "/path"); !reflect.DeepEqual(got, []string{"log", "grp"}) {
+			t.Errorf("unexpected chain: %v", got)
+		}
+	})
+
+	t.Run("conditional named middleware can be excluded", func(t *testing.T) {
+		var calls []string
+		rtr := routegroup.New(http.NewServeMux())
+		always := func(*http.Request) bool { return true }
+		rtr.UseIf(always, routegroup.Named("auth", trace("auth", &calls)))
+		rtr.HandleFunc("/private", ok)
+		rtr.Without("auth").HandleFunc("/public", ok)
+
+		if got := serve(t, rtr, &calls, "/private"); !reflect.DeepEqual(got, []string{"auth"}) {
+			t.Errorf("unexpected chain for private route: %v", got)
+		}
+		if got := serve(t, rtr, &calls, "/public"); len(got) != 0 {
+			t.Errorf("unexpected chain for public route: %v", got)
+		}
+	})
+}
+
+func TestRootChainComposedOnce(t *testing.T) {
+	built := map[string]int{}
+	var order []string
+	mw := func(name string) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			built[name]++
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	rtr := routegroup.New(http.NewServeMux())
+	rtr.Use(mw("first"))
+	rtr.NotFoundHandler(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusNotFound) })
+	child := rtr.Group()
+	child.HandleFunc("/a", func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) })
+
+	serve := func(path string) {
+		order = nil
+		rtr.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, http.NoBody))
+	}
+	for i := 0; i < 3; i++ {
+		serve("/a")
+		serve("/unknown")
+	}
+	if built["first"] != 2 { // one chain for routes, one for unmatched requests
+		t.Errorf("expected root middleware to be composed 2 times, got %d", built["first"])
+	}
+
+	// root middleware added after child routes invalidates the cached chains
+	rtr.Use(mw("second"))
+	serve("/a")
+	if !reflect.DeepEqual(order, []string{"first", "second"}) {
+		t.Errorf("unexpected order after Use: %v", order)
+	}
+	serve("/unknown")
+	if !reflect.DeepEqual(order, []string{"first", "second"}) {
+		t.Errorf("unexpected order for unmatched after Use: %v", order)
+	}
+	if built["first"] != 4 || built["second"] != 2 {
+		t.Errorf("unexpected compositions after Use: %v", built)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/go-pkgz/routegroup"
@@ -400,3 +401,53 @@ func TestCustomNotFoundVsMethodNotAllowed(t *testing.T) {
 		}
 	})
 }
+
+func TestCustomNotFoundHeaders(t *testing.T) {
+	rtr := routegroup.New(http.NewServeMux())
+	rtr.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Root", "true")
+			next.ServeHTTP(w, r)
+		})
+	})
+	rtr.NotFoundHandler(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"error":"not found"}`))
+	})
+	rtr.HandleFunc("GET /items", func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) })
+
+	t.Run("404 has no headers of the mux response", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		rtr.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/unknown", http.NoBody))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("expected 404, got %d", rec.Code)
+		}
+		if body := rec.Body.String(); body != `{"error":"not found"}` {
+			t.Errorf("unexpected body %q", body)
+		}
+		if h := rec.Header().Get("X-Content-Type-Options"); h != "" {
+			t.Errorf("unexpected X-Content-Type-Options header %q", h)
+		}
+		if h := rec.Header().Get("X-Root"); h != "true" {
+			t.Errorf("expected X-Root header, got %q", h)
+		}
+	})
+
+	t.Run("405 keeps mux and middleware headers", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		rtr.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/items", http.NoBody))
+		if rec.Code != http.StatusMethodNotAllowed {
+			t.Errorf("expected 405, got %d", rec.Code)
+		}
+		if h := rec.Header().Get("Allow"); !strings.Contains(h, http.MethodGet) {
+			t.Errorf("expected Allow header with GET, got %q", h)
+		}
+		if h := rec.Header().Get("X-Root"); h != "true" {
+			t.Errorf("expected X-Root header, got %q", h)
+		}
+		if body := rec.Body.String(); body != "Method Not Allowed\n" {
+			t.Errorf("unexpected body %q", body)
+		}
+	})
+}
@@ -0,0 +1,97 @@
+package routegroup
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Policy declares middlewares every route under a path prefix must have in its chain.
+// It is checked by Validate against the routes reported by Routes.
+type Policy struct {
+	Prefix      string   // path prefix the policy applies to, e.g. "/api"; empty applies to all routes
+	Except      []string // path prefixes excluded from the policy, e.g. "/api/health"
+	Middlewares []string // names of the required middlewares, see Named
+}
+
+// PolicyViolation describes a route missing middlewares required by a policy.
+type PolicyViolation struct {
+	Route   RouteInfo
+	Policy  Policy
+	Missing []string // names of the required middlewares absent from the route's chain
+}
+
+// ValidationError is returned by Validate and lists all policy violations.
+type ValidationError struct {
+	Violations []PolicyViolation
+}
+
+// Error returns a description of all violations, one route per line.
+func (e *ValidationError) Error() string {
+	lines := make([]string, 0, len(e.Violations)+1)
+	lines = append(lines, fmt.Sprintf("routegroup: %d route(s) violate middleware policies", len(e.Violations)))
+	for _, v := range e.Violations {
+		lines = append(lines, fmt.Sprintf("%q under %q is missing %s", v.Route.Pattern, v.Policy.Prefix, strings.Join(v.Missing, ", ")))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Require adds a policy to the root bundle. Policies are checked by Validate, so a route added
+// to a group lacking the required middleware, e.g. created before the With(auth) call, is caught.
+func (b *Bundle) Require(policy Policy) {
+	mu := b.treeLock()
+	mu.Lock()
+	defer mu.Unlock()
+	b.checkFrozen()
+	root := b.rootBundle()
+	root.policies = append(root.policies, policy)
+}
+
+// Validate checks all registered routes against the policies added with Require.
+// It returns *ValidationError listing the violating routes, or nil if all routes comply.
+func (b *Bundle) Validate() error {
+	mu := b.treeLock()
+	mu.Lock()
+	defer mu.Unlock()
+	return b.validate()
+}
+
+// validate checks routes against policies for Validate and Freeze.
+func (b *Bundle) validate() error {
+	root := b.rootBundle()
+	var violations []PolicyViolation
+	for _, rt := range root.routeInfos() {
+		path := rt.Path
+		if i := strings.Index(path, "/"); i > 0 {
+			path = path[i:] // drop host part of the pattern
+		}
+		for _, p := range root.policies {
+			if !hasPathPrefix(path, p.Prefix) || slices.ContainsFunc(p.Except, func(e string) bool { return hasPathPrefix(path, e) }) {
+				continue
+			}
+			var missing []string
+			for _, name := range p.Middlewares {
+				if !slices.Contains(rt.Middlewares, name) {
+					missing = append(missing, name)
+				}
+			}
+			if len(missing) > 0 {
+				violations = append(violations, PolicyViolation{Route: rt, Policy: p, Missing: missing})
+			}
+		}
+	}
+	if len(violations) > 0 {
+		return &ValidationError{Violations: violations}
+	}
+	return nil
+}
+
+// hasPathPrefix checks if the path is under the prefix, matching whole segments only,
+// i.e. "/api" covers "/api" and "/api/users" but not "/apidocs".
+func hasPathPrefix(path, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if prefix == "" {
+		return true
+	}
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
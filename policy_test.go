@@ -0,0 +1,121 @@
+package routegroup_test
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-pkgz/routegroup"
+)
+
+func TestValidate(t *testing.T) {
+	ok := func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) }
+	auth := routegroup.Named("auth", passThrough)
+	audit := routegroup.Named("audit", passThrough)
+
+	t.Run("all routes comply", func(t *testing.T) {
+		rtr := routegroup.New(http.NewServeMux())
+		rtr.Require(routegroup.Policy{Prefix: "/api", Except: []string{"/api/health"}, Middlewares: []string{"auth"}})
+		api := rtr.Mount("/api")
+		api.HandleFunc("GET /health", ok)
+		api.With(auth).HandleFunc("GET /users", ok)
+		rtr.HandleFunc("GET /apidocs", ok) // not under /api
+		if err := rtr.Validate(); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("no policies", func(t *testing.T) {
+		rtr := routegroup.New(http.NewServeMux())
+		rtr.HandleFunc("GET /users", ok)
+		if err := rtr.Validate(); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("violations reported", func(t *testing.T) {
+		rtr := routegroup.New(http.NewServeMux())
+		api := rtr.Mount("/api")
+		early := api.Group() // created before auth is added
+		api.Use(auth)
+		api.HandleFunc("GET /users", ok)
+		early.HandleFunc("GET /orders", ok)
+		api.Without("auth").HandleFunc("GET /health", ok)
+		api.Without("auth").HandleFunc("GET /public", ok)
+
+		// policy declared from a child bundle goes to the root
+		api.Require(routegroup.Policy{Prefix: "/api/", Except: []string{"/api/health"}, Middlewares: []string{"auth", "audit"}})
+		rtr.Require(routegroup.Policy{Middlewares: []string{"auth"}})
+
+		err := rtr.Validate()
+		var verr *routegroup.ValidationError
+		if !errors.As(err, &verr) {
+			t.Fatalf("expected ValidationError, got %v", err)
+		}
+
+		type violation struct {
+			pattern string
+			missing []string
+		}
+		var got []violation
+		for _, v := range verr.Violations {
+			got = append(got, violation{pattern: v.Route.Pattern, missing: v.Missing})
+		}
+		expected := []violation{
+			{pattern: "GET /api/users", missing: []string{"audit"}},
+			{pattern: "GET /api/orders", missing: []string{"auth", "audit"}},
+			{pattern: "GET /api/orders", missing: []string{"auth"}},
+			{pattern: "GET /api/health", missing: []string{"auth"}},
+			{pattern: "GET /api/public", missing: []string{"auth", "audit"}},
+			{pattern: "GET /api/public", missing: []string{"auth"}},
+		}
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("unexpected violations\nwant: %+v\ngot:  %+v", expected, got)
+		}
+		if !strings.Contains(err.Error(), `"GET /api/orders" under "/api/" is missing auth, audit`) {
+			t.Errorf("unexpected error message: %s", err.Error())
+		}
+	})
+
+	t.Run("root middleware satisfies policy", func(t *testing.T) {
+		rtr := routegroup.New(http.NewServeMux())
+		rtr.Use(auth)
+		rtr.Require(routegroup.Policy{Prefix: "/admin", Middlewares: []string{"auth", "audit"}})
+		admin := rtr.Mount("/admin")
+		admin.With(audit).HandleFunc("GET /", ok)
+		admin.With(audit).HandleRoot("GET", http.HandlerFunc(ok))
+		if err := rtr.Validate(); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("conditional middleware does not satisfy policy", func(t *testing.T) {
+		deny := func(http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusUnauthorized) })
+		}
+		rtr := routegroup.New(http.NewServeMux())
+		rtr.Require(routegroup.Policy{Prefix: "/api", Middlewares: []string{"auth"}})
+		rtr.Mount("/api").WithIf(func(*http.Request) bool { return false }, routegroup.Named("auth", deny)).
+			HandleFunc("GET /secret", ok)
+
+		err := rtr.Validate()
+		var verr *routegroup.ValidationError
+		if !errors.As(err, &verr) || len(verr.Violations) != 1 || !reflect.DeepEqual(verr.Violations[0].Missing, []string{"auth"}) {
+			t.Fatalf("expected auth missing, got %v", err)
+		}
+		if got := verr.Violations[0].Route.Middlewares; !reflect.DeepEqual(got, []string{"auth (conditional)"}) {
+			t.Errorf("unexpected middlewares %v", got)
+		}
+	})
+
+	t.Run("host patterns", func(t *testing.T) {
+		rtr := routegroup.New(http.NewServeMux())
+		rtr.Require(routegroup.Policy{Prefix: "/api", Middlewares: []string{"auth"}})
+		rtr.HandleFunc("GET example.com/api/users", ok)
+		if err := rtr.Validate(); err == nil {
+			t.Error("expected violation for host pattern")
+		}
+	})
+}
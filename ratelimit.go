@@ -0,0 +1,184 @@
+package routegroup
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Rate is a token bucket limit: Requests per Period on average, with bursts up to Burst requests.
+type Rate struct {
+	Requests int
+	Period   time.Duration
+	Burst    int // bucket capacity, Requests if zero
+}
+
+// capacity returns the bucket capacity.
+func (r Rate) capacity() float64 {
+	if r.Burst > 0 {
+		return float64(r.Burst)
+	}
+	return float64(r.Requests)
+}
+
+// perSecond returns the refill rate in tokens per second.
+func (r Rate) perSecond() float64 {
+	return float64(r.Requests) / r.Period.Seconds()
+}
+
+// RateLimitResult is the outcome of taking a token from a bucket.
+type RateLimitResult struct {
+	Allowed    bool
+	Remaining  int           // tokens left in the bucket
+	Reset      time.Duration // time until the bucket is full again
+	RetryAfter time.Duration // time until the next token is available, if not allowed
+}
+
+// RateLimitStore keeps token buckets for RateLimit. Stores shared between processes can be
+// plugged in by implementing it, the in-memory one made with NewMemoryRateLimitStore is the default.
+type RateLimitStore interface {
+	// Take takes a token from the bucket with the key, creating a full bucket if there is none.
+	Take(ctx context.Context, key string, rate Rate) (RateLimitResult, error)
+}
+
+// RateLimitOpts configures RateLimit.
+type RateLimitOpts struct {
+	Rate Rate
+
+	// Key returns the key of the client to limit, RateLimitByIP by default. Requests with the same key
+	// share a bucket for each route pattern.
+	Key func(r *http.Request) string
+
+	// Store keeps the buckets, a new in-memory store by default.
+	Store RateLimitStore
+}
+
+// RateLimit returns a new Group limiting the request rate of routes registered on it, typically used
+// for a group or, with the returned group, for a single route. Each route pattern has its own buckets,
+// keyed by the client key, so requests to different URLs of the same route share the limit.
+// Responses have RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers, and requests over
+// the limit are rejected with 429 and Retry-After, rendered with the custom ErrorHandler if set.
+// If the store fails, requests are allowed. The middleware is named "rate_limit" and the limit is
+// reported by Routes.
+func (b *Bundle) RateLimit(opts RateLimitOpts) *Bundle {
+	if opts.Rate.Requests <= 0 || opts.Rate.Period <= 0 {
+		panic("routegroup: rate limit needs positive requests and period")
+	}
+	if opts.Key == nil {
+		opts.Key = RateLimitByIP
+	}
+	if opts.Store == nil {
+		opts.Store = NewMemoryRateLimitStore()
+	}
+	rate := opts.Rate
+	limit := strconv.Itoa(int(rate.capacity()))
+
+	mu := b.treeLock()
+	mu.Lock()
+	defer mu.Unlock()
+	nb := b.clone()
+	nb.middlewares = append(nb.middlewares, &middleware{rate: &rate, fn: Named("rate_limit", func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			res, err := opts.Store.Take(r.Context(), r.Pattern+"\x00"+opts.Key(r), rate)
+			if err != nil {
+				next.ServeHTTP(w, r) // fail open, the limit is not essential for serving
+				return
+			}
+			h := w.Header()
+			h.Set("RateLimit-Limit", limit)
+			h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+			if !res.Allowed {
+				h.Set("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+				nb.renderError(w, r, http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	})})
+	return nb
+}
+
+// RateLimitByIP keys requests by the client IP, as resolved by the ClientIP middleware if used.
+func RateLimitByIP(r *http.Request) string { return clientIP(r) }
+
+// RateLimitByHeader keys requests by the value of the header, e.g. an API key.
+// Requests without the header share a bucket.
+func RateLimitByHeader(name string) func(r *http.Request) string {
+	return func(r *http.Request) string { return r.Header.Get(name) }
+}
+
+// RateLimitByPathValue keys requests by the path value of the route wildcard with the name,
+// e.g. "apiKey" for "GET /keys/{apiKey}/data".
+func RateLimitByPathValue(name string) func(r *http.Request) string {
+	return func(r *http.Request) string { return r.PathValue(name) }
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// MemoryRateLimitStore is an in-memory RateLimitStore. Buckets which are full again are dropped
+// periodically, so memory is used only for recently active keys.
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+	rate   Rate
+}
+
+// NewMemoryRateLimitStore makes an empty in-memory store.
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{buckets: make(map[string]*tokenBucket), lastSweep: time.Now()}
+}
+
+// Take takes a token from the bucket with the key.
+func (s *MemoryRateLimitStore) Take(_ context.Context, key string, rate Rate) (RateLimitResult, error) {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastSweep) > time.Minute {
+		s.sweep(now)
+	}
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: rate.capacity(), last: now, rate: rate}
+		s.buckets[key] = b
+	}
+	b.refill(now)
+
+	res := RateLimitResult{Allowed: b.tokens >= 1}
+	if res.Allowed {
+		b.tokens--
+	} else {
+		res.RetryAfter = time.Duration((1 - b.tokens) / rate.perSecond() * float64(time.Second))
+	}
+	res.Remaining = int(b.tokens)
+	res.Reset = time.Duration((rate.capacity() - b.tokens) / rate.perSecond() * float64(time.Second))
+	return res, nil
+}
+
+// sweep drops buckets which are full again.
+func (s *MemoryRateLimitStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		b.refill(now)
+		if b.tokens >= b.rate.capacity() {
+			delete(s.buckets, key)
+		}
+	}
+	s.lastSweep = now
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	b.tokens = math.Min(b.rate.capacity(), b.tokens+now.Sub(b.last).Seconds()*b.rate.perSecond())
+	b.last = now
+}
@@ -0,0 +1,134 @@
+package routegroup_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-pkgz/routegroup"
+)
+
+func TestRateLimit(t *testing.T) {
+	ok := func(w http.ResponseWriter, _ *http.Request) { _, _ = w.Write([]byte("ok")) }
+	serve := func(rtr http.Handler, method, path string, mod func(*http.Request)) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, http.NoBody)
+		if mod != nil {
+			mod(req)
+		}
+		rec := httptest.NewRecorder()
+		rtr.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("by ip and pattern", func(t *testing.T) {
+		rtr := routegroup.New(http.NewServeMux())
+		api := rtr.Mount("/api").RateLimit(routegroup.RateLimitOpts{Rate: routegroup.Rate{Requests: 2, Period: time.Hour}})
+		api.HandleFunc("GET /users/{id}", ok)
+		api.HandleFunc("GET /orders", ok)
+		rtr.HandleFunc("GET /health", ok)
+
+		for i, path := range []string{"/api/users/1", "/api/users/2"} { // different URLs, same pattern
+			rec := serve(rtr, "GET", path, nil)
+			if rec.Code != http.StatusOK || rec.Header().Get("RateLimit-Limit") != "2" ||
+				rec.Header().Get("RateLimit-Remaining") != []string{"1", "0"}[i] {
+				t.Errorf("%s: unexpected response %d %v", path, rec.Code, rec.Header())
+			}
+		}
+		rec := serve(rtr, "GET", "/api/users/3", nil)
+		if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "1800" ||
+			rec.Header().Get("RateLimit-Reset") != "3600" {
+			t.Errorf("unexpected response %d %v", rec.Code, rec.Header())
+		}
+
+		if rec = serve(rtr, "GET", "/api/orders", nil); rec.Code != http.StatusOK {
+			t.Errorf("other pattern limited: %d", rec.Code)
+		}
+		other := func(r *http.Request) { r.RemoteAddr = "10.0.0.2:1234" }
+		if rec = serve(rtr, "GET", "/api/users/1", other); rec.Code != http.StatusOK {
+			t.Errorf("other client limited: %d", rec.Code)
+		}
+		if rec = serve(rtr, "GET", "/health", nil); rec.Code != http.StatusOK || rec.Header().Get("RateLimit-Limit") != "" {
+			t.Errorf("route outside the group limited: %d", rec.Code)
+		}
+	})
+
+	t.Run("by header and path value", func(t *testing.T) {
+		rtr := routegroup.New(http.NewServeMux())
+		rtr.RateLimit(routegroup.RateLimitOpts{Rate: routegroup.Rate{Requests: 1, Period: time.Minute},
+			Key: routegroup.RateLimitByHeader("X-Api-Key")}).HandleFunc("GET /h", ok)
+		rtr.RateLimit(routegroup.RateLimitOpts{Rate: routegroup.Rate{Requests: 1, Period: time.Minute},
+			Key: routegroup.RateLimitByPathValue("apiKey")}).HandleFunc("GET /keys/{apiKey}", ok)
+
+		key := func(k string) func(*http.Request) { return func(r *http.Request) { r.Header.Set("X-Api-Key", k) } }
+		codes := []int{
+			serve(rtr, "GET", "/h", key("a")).Code, serve(rtr, "GET", "/h", key("a")).Code, serve(rtr, "GET", "/h", key("b")).Code,
+			serve(rtr, "GET", "/keys/a", nil).Code, serve(rtr, "GET", "/keys/a", nil).Code, serve(rtr, "GET", "/keys/b", nil).Code,
+		}
+		want := []int{200, 429, 200, 200, 429, 200}
+		for i := range want {
+			if codes[i] != want[i] {
+				t.Errorf("request %d: got %d, want %d", i, codes[i], want[i])
+			}
+		}
+	})
+
+	t.Run("burst and refill", func(t *testing.T) {
+		store := routegroup.NewMemoryRateLimitStore()
+		rate := routegroup.Rate{Requests: 100, Period: time.Second, Burst: 3}
+		for i := range 3 {
+			if res, _ := store.Take(context.Background(), "k", rate); !res.Allowed || res.Remaining != 2-i {
+				t.Errorf("take %d: %+v", i, res)
+			}
+		}
+		res, _ := store.Take(context.Background(), "k", rate)
+		if res.Allowed || res.RetryAfter <= 0 || res.RetryAfter > 10*time.Millisecond {
+			t.Errorf("expected rejection, got %+v", res)
+		}
+		time.Sleep(20 * time.Millisecond)
+		if res, _ = store.Take(context.Background(), "k", rate); !res.Allowed {
+			t.Errorf("expected refill, got %+v", res)
+		}
+	})
+
+	t.Run("store failure allows requests", func(t *testing.T) {
+		rtr := routegroup.New(http.NewServeMux())
+		rtr.RateLimit(routegroup.RateLimitOpts{Rate: routegroup.Rate{Requests: 1, Period: time.Minute}, Store: failingStore{}}).
+			HandleFunc("GET /x", ok)
+		if rec := serve(rtr, "GET", "/x", nil); rec.Code != http.StatusOK {
+			t.Errorf("unexpected status %d", rec.Code)
+		}
+	})
+
+	t.Run("introspection", func(t *testing.T) {
+		rtr := routegroup.New(http.NewServeMux())
+		limited := rtr.RateLimit(routegroup.RateLimitOpts{Rate: routegroup.Rate{Requests: 5, Period: time.Second}})
+		limited.HandleFunc("GET /limited", ok)
+		limited.Without("rate_limit").HandleFunc("GET /unlimited", ok)
+		routes := rtr.Routes()
+		if rl := routes[0].RateLimit; rl == nil || *rl != (routegroup.Rate{Requests: 5, Period: time.Second}) ||
+			len(routes[0].Middlewares) != 1 || routes[0].Middlewares[0] != "rate_limit" {
+			t.Errorf("unexpected route info %+v", routes[0])
+		}
+		if routes[1].RateLimit != nil {
+			t.Errorf("unexpected rate limit %+v", routes[1].RateLimit)
+		}
+	})
+
+	t.Run("invalid rate", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected panic")
+			}
+		}()
+		routegroup.New(http.NewServeMux()).RateLimit(routegroup.RateLimitOpts{})
+	})
+}
+
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, routegroup.Rate) (routegroup.RateLimitResult, error) {
+	return routegroup.RateLimitResult{}, errors.New("store down")
+}
@@ -0,0 +1,64 @@
+package routegroup
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"regexp"
+)
+
+// RequestIDOpts configures RequestID. The zero value uses the "X-Request-ID" header, accepts incoming
+// IDs matching DefaultRequestIDPattern and generates random hex IDs.
+type RequestIDOpts struct {
+	Header   string         // request and response header with the ID, "X-Request-ID" by default
+	Validate *regexp.Regexp // incoming IDs not matching it are replaced, DefaultRequestIDPattern by default
+	Generate func() string  // makes a new ID, 32 random hex characters by default
+}
+
+// DefaultRequestIDPattern accepts IDs up to 128 characters long made of letters, digits and "._:+/=-",
+// which covers UUIDs, hex and base64 IDs.
+var DefaultRequestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:+/=-]{1,128}$`)
+
+type requestIDKey struct{}
+
+// RequestID makes a middleware assigning an ID to each request. The incoming ID from the header is
+// kept if valid, otherwise a new one is generated. The ID is stored in the request context, see
+// RequestIDFrom, and set on the response header. AccessLog logs it even if it runs before this
+// middleware. The middleware is named "request_id".
+func RequestID(opts RequestIDOpts) func(http.Handler) http.Handler {
+	if opts.Header == "" {
+		opts.Header = "X-Request-ID"
+	}
+	if opts.Validate == nil {
+		opts.Validate = DefaultRequestIDPattern
+	}
+	if opts.Generate == nil {
+		opts.Generate = newRequestID
+	}
+	return Named("request_id", func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(opts.Header)
+			if id == "" || !opts.Validate.MatchString(id) {
+				id = opts.Generate()
+			}
+			w.Header().Set(opts.Header, id)
+			if c, ok := r.Context().Value(routeCaptureKey{}).(*routeCapture); ok {
+				c.requestID = id // for the access log running before this middleware
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+		})
+	})
+}
+
+// RequestIDFrom returns the request ID set by the RequestID middleware, empty if there is none.
+func RequestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b) // never returns an error
+	return hex.EncodeToString(b)
+}
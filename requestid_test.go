@@ -0,0 +1,90 @@
+package routegroup_test
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/go-pkgz/routegroup"
+)
+
+func TestRequestID(t *testing.T) {
+	var seen string
+	handler := func(_ http.ResponseWriter, r *http.Request) { seen = routegroup.RequestIDFrom(r.Context()) }
+
+	t.Run("defaults", func(t *testing.T) {
+		rtr := routegroup.New(http.NewServeMux())
+		rtr.Use(routegroup.RequestID(routegroup.RequestIDOpts{}))
+		rtr.HandleFunc("GET /", handler)
+
+		tbl := []struct {
+			name, incoming string
+			keep           bool
+		}{
+			{"valid incoming", "3f2a9c1e-7d4b-4c8e-9b1a-0e5f6d7c8b9a", true},
+			{"missing", "", false},
+			{"invalid", "bad id\nwith newline", false},
+			{"too long", strings.Repeat("a", 129), false},
+		}
+		for _, tt := range tbl {
+			t.Run(tt.name, func(t *testing.T) {
+				req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+				if tt.incoming != "" {
+					req.Header.Set("X-Request-ID", tt.incoming)
+				}
+				rec := httptest.NewRecorder()
+				rtr.ServeHTTP(rec, req)
+				got := rec.Header().Get("X-Request-ID")
+				if got != seen {
+					t.Errorf("response header %q differs from context %q", got, seen)
+				}
+				if tt.keep && got != tt.incoming {
+					t.Errorf("expected incoming id kept, got %q", got)
+				}
+				if !tt.keep && !regexp.MustCompile(`^[0-9a-f]{32}$`).MatchString(got) {
+					t.Errorf("expected generated id, got %q", got)
+				}
+			})
+		}
+	})
+
+	t.Run("custom", func(t *testing.T) {
+		rtr := routegroup.New(http.NewServeMux())
+		rtr.Use(routegroup.RequestID(routegroup.RequestIDOpts{
+			Header:   "X-Trace",
+			Validate: regexp.MustCompile(`^t-\d+$`),
+			Generate: func() string { return "t-1" },
+		}))
+		rtr.HandleFunc("GET /", handler)
+
+		req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+		req.Header.Set("X-Trace", "t-42")
+		rec := httptest.NewRecorder()
+		rtr.ServeHTTP(rec, req)
+		if seen != "t-42" || rec.Header().Get("X-Trace") != "t-42" {
+			t.Errorf("expected incoming id, got %q", seen)
+		}
+
+		req.Header.Set("X-Trace", "x-42")
+		rtr.ServeHTTP(httptest.NewRecorder(), req)
+		if seen != "t-1" {
+			t.Errorf("expected generated id, got %q", seen)
+		}
+	})
+
+	t.Run("access log picks it up", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		rtr := routegroup.New(http.NewServeMux())
+		rtr.Use(routegroup.AccessLog(routegroup.AccessLogOpts{Logger: slog.New(slog.NewTextHandler(buf, nil))}))
+		rtr.Use(routegroup.RequestID(routegroup.RequestIDOpts{Header: "X-Trace", Generate: func() string { return "gen-1" }}))
+		rtr.HandleFunc("GET /", handler)
+		rtr.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+		if !strings.Contains(buf.String(), "request_id=gen-1") {
+			t.Errorf("request id not logged: %s", buf.String())
+		}
+	})
+}
@@ -0,0 +1,121 @@
+package routegroup
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+// ResponseWriter wraps http.ResponseWriter recording the response status, the number of body bytes
+// and the time of the first write, for middlewares which need them after the handler returns, e.g. for
+// logging or metrics. It keeps optional interfaces of the wrapped writer working: it implements
+// http.Flusher, http.Hijacker and io.ReaderFrom, and Unwrap lets http.ResponseController reach
+// the underlying writer, e.g. for deadlines, so streaming responses and websocket upgrades
+// work through middlewares using it.
+type ResponseWriter struct {
+	http.ResponseWriter
+	status     int
+	bytes      int64
+	firstWrite time.Time
+	hijacked   bool
+}
+
+// NewResponseWriter wraps the writer. If the writer is a *ResponseWriter already, it's returned as is,
+// so nested middlewares share the same recording.
+func NewResponseWriter(w http.ResponseWriter) *ResponseWriter {
+	if rw, ok := w.(*ResponseWriter); ok {
+		return rw
+	}
+	return &ResponseWriter{ResponseWriter: w}
+}
+
+// Status returns the response status. It's 200 if nothing was written yet, as net/http responds
+// with 200 if the handler writes nothing, and 101 for hijacked connections without a status written.
+func (w *ResponseWriter) Status() int {
+	if w.status == 0 {
+		if w.hijacked {
+			return http.StatusSwitchingProtocols
+		}
+		return http.StatusOK
+	}
+	return w.status
+}
+
+// BytesWritten returns the number of response body bytes written.
+func (w *ResponseWriter) BytesWritten() int64 { return w.bytes }
+
+// FirstWrite returns the time the header or body was first written, zero if nothing was written.
+// Informational 1xx headers don't count.
+func (w *ResponseWriter) FirstWrite() time.Time { return w.firstWrite }
+
+// Written reports whether the header or body was written.
+func (w *ResponseWriter) Written() bool { return w.status != 0 }
+
+// Hijacked reports whether the connection was hijacked.
+func (w *ResponseWriter) Hijacked() bool { return w.hijacked }
+
+// WriteHeader records the first non-informational status and writes the header.
+func (w *ResponseWriter) WriteHeader(status int) {
+	if w.status == 0 && (status >= 200 || status == http.StatusSwitchingProtocols) {
+		w.record(status)
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write writes the body, recording 200 status if no header was written.
+func (w *ResponseWriter) Write(b []byte) (int, error) {
+	w.record(http.StatusOK)
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// ReadFrom copies the body from the reader using io.ReaderFrom of the wrapped writer if available,
+// e.g. to let http.ServeContent use sendfile.
+func (w *ResponseWriter) ReadFrom(r io.Reader) (int64, error) {
+	w.record(http.StatusOK)
+	var n int64
+	var err error
+	if rf, ok := w.ResponseWriter.(io.ReaderFrom); ok {
+		n, err = rf.ReadFrom(r)
+	} else {
+		n, err = io.Copy(struct{ io.Writer }{w.ResponseWriter}, r)
+	}
+	w.bytes += n
+	return n, err
+}
+
+// Flush sends buffered data to the client, ignoring errors, see FlushError.
+func (w *ResponseWriter) Flush() {
+	_ = w.FlushError()
+}
+
+// FlushError sends buffered data to the client. It returns http.ErrNotSupported
+// if the wrapped writer doesn't support flushing. http.ResponseController uses it for Flush.
+func (w *ResponseWriter) FlushError() error {
+	w.record(http.StatusOK)
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Hijack lets the caller take over the connection, e.g. for websockets. It returns http.ErrNotSupported
+// if the wrapped writer doesn't support hijacking.
+func (w *ResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err == nil {
+		w.hijacked = true
+	}
+	return conn, rw, err
+}
+
+// Unwrap returns the wrapped writer for http.ResponseController.
+func (w *ResponseWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+// record keeps the status and time of the first write.
+func (w *ResponseWriter) record(status int) {
+	if w.status == 0 {
+		w.status = status
+		w.firstWrite = time.Now()
+	}
+}
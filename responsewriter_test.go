@@ -0,0 +1,196 @@
+package routegroup_test
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-pkgz/routegroup"
+)
+
+func TestResponseWriter(t *testing.T) {
+	t.Run("records status bytes and first write", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		rw := routegroup.NewResponseWriter(rec)
+		if rw.Written() || rw.Status() != http.StatusOK || !rw.FirstWrite().IsZero() {
+			t.Errorf("unexpected initial state: %d %v", rw.Status(), rw.FirstWrite())
+		}
+		before := time.Now()
+		rw.WriteHeader(http.StatusAccepted)
+		rw.WriteHeader(http.StatusInternalServerError) // superfluous, ignored
+		_, _ = rw.Write([]byte("hello"))
+		_, _ = io.WriteString(rw, " world")
+		if rw.Status() != http.StatusAccepted || rw.BytesWritten() != 11 || rw.FirstWrite().Before(before) {
+			t.Errorf("got status %d, bytes %d, first write %v", rw.Status(), rw.BytesWritten(), rw.FirstWrite())
+		}
+		if rec.Code != http.StatusAccepted || rec.Body.String() != "hello world" {
+			t.Errorf("unexpected response %d %q", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("informational header", func(t *testing.T) {
+		rw := routegroup.NewResponseWriter(httptest.NewRecorder())
+		rw.WriteHeader(http.StatusEarlyHints)
+		if rw.Written() || !rw.FirstWrite().IsZero() {
+			t.Error("informational header recorded")
+		}
+		rw.WriteHeader(http.StatusNoContent)
+		if rw.Status() != http.StatusNoContent {
+			t.Errorf("unexpected status %d", rw.Status())
+		}
+	})
+
+	t.Run("write without header", func(t *testing.T) {
+		rw := routegroup.NewResponseWriter(httptest.NewRecorder())
+		n, err := rw.ReadFrom(strings.NewReader("body"))
+		if err != nil || n != 4 || rw.BytesWritten() != 4 || !rw.Written() || rw.Status() != http.StatusOK {
+			t.Errorf("got %d %v, status %d, bytes %d", n, err, rw.Status(), rw.BytesWritten())
+		}
+	})
+
+	t.Run("nested wrappers share recording", func(t *testing.T) {
+		rw := routegroup.NewResponseWriter(httptest.NewRecorder())
+		if routegroup.NewResponseWriter(rw) != rw {
+			t.Error("expected the same wrapper")
+		}
+	})
+
+	t.Run("flush and unwrap", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		rw := routegroup.NewResponseWriter(rec)
+		var w http.ResponseWriter = rw
+		if _, ok := w.(http.Flusher); !ok {
+			t.Fatal("not a flusher")
+		}
+		if err := http.NewResponseController(w).Flush(); err != nil {
+			t.Errorf("flush failed: %v", err)
+		}
+		if !rec.Flushed || rw.Status() != http.StatusOK {
+			t.Error("not flushed")
+		}
+		if rw.Unwrap() != rec {
+			t.Error("unexpected unwrapped writer")
+		}
+		if err := http.NewResponseController(w).SetWriteDeadline(time.Now()); !errors.Is(err, http.ErrNotSupported) {
+			t.Errorf("expected not supported from recorder, got %v", err)
+		}
+		if _, _, err := rw.Hijack(); !errors.Is(err, http.ErrNotSupported) {
+			t.Errorf("expected not supported from recorder, got %v", err)
+		}
+	})
+}
+
+func TestResponseWriterThroughMiddleware(t *testing.T) {
+	type result struct {
+		status   int
+		hijacked bool
+	}
+	results := make(chan result, 1)
+	rtr := routegroup.New(http.NewServeMux())
+	rtr.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rw := routegroup.NewResponseWriter(w)
+			next.ServeHTTP(rw, r)
+			results <- result{status: rw.Status(), hijacked: rw.Hijacked()}
+		})
+	})
+	rtr.HandleFunc("GET /events", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := range 3 {
+			_, _ = io.WriteString(w, "data: "+string(rune('a'+i))+"\n\n")
+			w.(http.Flusher).Flush()
+		}
+	})
+	rtr.HandleFunc("GET /upgrade", func(w http.ResponseWriter, _ *http.Request) {
+		if err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(time.Second)); err != nil {
+			t.Errorf("deadline: %v", err)
+		}
+		conn, brw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Errorf("hijack: %v", err)
+			return
+		}
+		defer conn.Close()
+		_, _ = brw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: echo\r\nConnection: Upgrade\r\n\r\n")
+		_ = brw.Flush()
+		line, _ := brw.ReadString('\n')
+		_, _ = brw.WriteString("echo " + line)
+		_ = brw.Flush()
+	})
+	ts := httptest.NewServer(rtr)
+	defer ts.Close()
+
+	t.Run("sse", func(t *testing.T) {
+		resp, err := http.Get(ts.URL + "/events")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		if string(body) != "data: a\n\ndata: b\n\ndata: c\n\n" {
+			t.Errorf("unexpected response %q", body)
+		}
+		if res := <-results; res.status != http.StatusOK || res.hijacked {
+			t.Errorf("unexpected recording %+v", res)
+		}
+	})
+
+	t.Run("upgrade", func(t *testing.T) {
+		conn, err := net.Dial("tcp", ts.Listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		_, _ = io.WriteString(conn, "GET /upgrade HTTP/1.1\r\nHost: x\r\nUpgrade: echo\r\nConnection: Upgrade\r\n\r\n")
+		br := bufio.NewReader(conn)
+		resp, err := http.ReadResponse(br, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusSwitchingProtocols {
+			t.Fatalf("unexpected status %d", resp.StatusCode)
+		}
+		_, _ = io.WriteString(conn, "ping\n")
+		line, _ := br.ReadString('\n')
+		if line != "echo ping\n" {
+			t.Errorf("unexpected echo %q", line)
+		}
+		_ = conn.Close()
+		if res := <-results; res.status != http.StatusSwitchingProtocols || !res.hijacked {
+			t.Errorf("unexpected recording %+v", res)
+		}
+	})
+}
+
+func TestResponseWriterNestedMiddlewares(t *testing.T) {
+	var statuses []int
+	capture := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rw := routegroup.NewResponseWriter(w)
+			next.ServeHTTP(rw, r)
+			statuses = append(statuses, rw.Status())
+		})
+	}
+
+	rtr := routegroup.New(http.NewServeMux())
+	rtr.Use(capture, capture)
+	rtr.Mount("/api").With(capture).HandleFunc("GET /test", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	rec := httptest.NewRecorder()
+	rtr.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/test", http.NoBody))
+	if rec.Code != http.StatusAccepted {
+		t.Errorf("expected status 202, got %d", rec.Code)
+	}
+	if len(statuses) != 3 || statuses[0] != http.StatusAccepted || statuses[1] != http.StatusAccepted ||
+		statuses[2] != http.StatusAccepted {
+		t.Errorf("unexpected captured statuses %v", statuses)
+	}
+}
@@ -0,0 +1,234 @@
+package routegroup
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync/atomic"
+)
+
+// ErrUnknownRoute is returned by DisableRoute, EnableRoute and ReplaceRoute for a pattern
+// not registered on the bundle's tree.
+var ErrUnknownRoute = errors.New("routegroup: unknown route")
+
+// RouteState is the runtime state of a route, see DisableRoute.
+type RouteState int
+
+// route states
+const (
+	RouteEnabled  RouteState = iota // route serves requests with its handler
+	RouteDisabled                   // route responds with the status given to DisableRoute
+)
+
+// String returns the state name, "enabled" or "disabled".
+func (s RouteState) String() string {
+	if s == RouteDisabled {
+		return "disabled"
+	}
+	return "enabled"
+}
+
+// RouteInfo describes a route registered with the bundle.
+type RouteInfo struct {
+	Pattern string // full pattern as registered with the mux, e.g. "GET /api/users/{id}"
+	Method  string // method part of the pattern, empty if the route matches all methods
+	Path    string // path part of the pattern, with host if the pattern has one
+
+	// Middlewares lists the middleware chain of the route in execution order: root middlewares
+	// first, then the group ones. Middlewares are reported by the name given with Named,
+	// unnamed ones by the name of their function. Middlewares added with UseIf or WithIf have
+	// the " (conditional)" suffix, e.g. "auth (conditional)", and don't satisfy policies.
+	Middlewares []string
+
+	State          RouteState // runtime state of the route
+	DisabledStatus int        // response status of a disabled route
+	Replaced       bool       // handler was swapped with ReplaceRoute
+
+	RateLimit   *Rate        // limit set with RateLimit, the innermost one if nested, nil if none
+	Deprecation *Deprecation // deprecation set with Deprecate, the innermost one if nested, nil if none
+
+	// Version is the API version of a route registered on a Version group, empty otherwise.
+	// Such routes are reported twice, with the version path prefix and with the unprefixed pattern
+	// selected by the version header or media type.
+	Version string
+
+	Consumes []string // request content types accepted by the route, set with Consumes, nil if any
+	Produces []string // response content types of the route, set with Produces, nil if not set
+}
+
+// route keeps registration details of a single route. It is the handler registered with the mux,
+// so the route can be disabled or have its handler replaced at runtime.
+type route struct {
+	pattern     string
+	wildcards   []string      // names of the pattern wildcards
+	middlewares []*middleware // group-level middlewares the handler is wrapped with
+	skip        []*middleware // root middlewares excluded for the route
+	root        *Bundle
+	cors        *corsPolicy // set if the route was registered on a group with CORS
+	version     string      // set if the route was registered on a Version group, except HandleFiles and HandleRoot
+	consumes    []string    // set if the route was registered on a Consumes group
+	produces    []string    // set if the route was registered on a Produces group
+	selectors   []selector  // constraints of a route sharing its pattern with other routes, see addVariant
+
+	// variants are all routes registered for the pattern, including this one, if there is more than one.
+	// set on the route registered with the mux only, replaced as a whole under the tree lock.
+	variants atomic.Pointer[[]*route]
+
+	handler  atomic.Pointer[handlerRef] // handler wrapped with group-level middlewares
+	disabled atomic.Int64               // response status if disabled, 0 if enabled
+	replaced atomic.Bool
+}
+
+// handlerRef holds a handler for atomic replacement.
+type handlerRef struct {
+	http.Handler
+}
+
+// ServeHTTP serves the request with the current handler of the route, or responds with
+// the status of a disabled route. For a pattern shared by several routes, e.g. versions, it serves
+// the request with the variant accepting it, or responds with the status of the mismatch.
+// Disabled routes and mismatches with 404 status use the custom 404 handler if set,
+// other statuses the custom error handler.
+func (rt *route) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	target := rt
+	status := int(rt.disabled.Load())
+	if status == 0 && (len(rt.selectors) > 0 || rt.variants.Load() != nil) {
+		target, status = rt.selectVariant(w, r)
+		if target == nil {
+			target = rt
+		}
+	}
+	target.capture(r)
+	if status == 0 {
+		target.handler.Load().ServeHTTP(w, r)
+		return
+	}
+	rt.root.renderStatus(w, r, status)
+}
+
+// DisableRoute makes the route with the given pattern respond with the status, typically 404 or 503,
+// instead of calling its handler. The pattern is the full pattern as reported by Routes.
+// Root middlewares run as for any other request, while the route's group middlewares and handler
+// are skipped. For a pattern shared by several routes, e.g. versions, all of them are disabled.
+// Unlike registration, it is allowed after Freeze.
+func (b *Bundle) DisableRoute(pattern string, status int) error {
+	if status < 400 || status > 599 {
+		return fmt.Errorf("routegroup: invalid status %d for disabled route %q, must be 4xx or 5xx", status, pattern)
+	}
+	rt, err := b.lookupRoute(pattern)
+	if err != nil {
+		return err
+	}
+	rt.disabled.Store(int64(status))
+	return nil
+}
+
+// EnableRoute re-enables the route disabled with DisableRoute.
+func (b *Bundle) EnableRoute(pattern string) error {
+	rt, err := b.lookupRoute(pattern)
+	if err != nil {
+		return err
+	}
+	rt.disabled.Store(0)
+	return nil
+}
+
+// ReplaceRoute atomically swaps the handler of the route with the given pattern. The new handler is
+// wrapped with the same group middlewares as the original one. In-flight requests finish with
+// the handler they started with. For a pattern shared by several routes, e.g. versions, the first
+// registered one is replaced. Unlike registration, it is allowed after Freeze.
+func (b *Bundle) ReplaceRoute(pattern string, handler http.Handler) error {
+	rt, err := b.lookupRoute(pattern)
+	if err != nil {
+		return err
+	}
+	h := handler
+	for i := len(rt.middlewares) - 1; i >= 0; i-- {
+		h = rt.middlewares[i].fn(h)
+	}
+	rt.handler.Store(&handlerRef{h})
+	rt.replaced.Store(true)
+	return nil
+}
+
+// lookupRoute finds the registered route by its full pattern.
+func (b *Bundle) lookupRoute(pattern string) (*route, error) {
+	mu := b.treeLock()
+	mu.Lock()
+	defer mu.Unlock()
+	if rt, ok := b.rootBundle().index[pattern]; ok {
+		return rt, nil
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownRoute, pattern)
+}
+
+// Routes returns all routes registered on the bundle's tree, i.e. on the root bundle and all groups
+// derived from it, in registration order. Routes registered directly on the underlying mux are not included.
+func (b *Bundle) Routes() []RouteInfo {
+	mu := b.treeLock()
+	mu.Lock()
+	defer mu.Unlock()
+	return b.routeInfos()
+}
+
+// routeInfos builds the list of registered routes for Routes.
+func (b *Bundle) routeInfos() []RouteInfo {
+	root := b.rootBundle()
+	res := make([]RouteInfo, 0, len(root.routes))
+	for _, rt := range root.routes {
+		info := RouteInfo{Pattern: rt.pattern, Path: rt.pattern, Version: rt.version,
+			Consumes: rt.consumes, Produces: rt.produces}
+		if matches := reGo122.FindStringSubmatch(rt.pattern); len(matches) > 2 {
+			info.Method, info.Path = matches[1], matches[2]
+		}
+		for _, mw := range root.globalMiddlewares(rt.skip) {
+			info.Middlewares = append(info.Middlewares, mw.label())
+		}
+		for _, mw := range rt.middlewares {
+			info.Middlewares = append(info.Middlewares, mw.label())
+			if mw.rate != nil {
+				info.RateLimit = mw.rate
+			}
+			if mw.deprecation != nil {
+				info.Deprecation = mw.deprecation
+			}
+		}
+		if status := int(root.index[rt.pattern].disabled.Load()); status != 0 { // disabled for all variants
+			info.State, info.DisabledStatus = RouteDisabled, status
+		}
+		info.Replaced = rt.replaced.Load()
+		res = append(res, info)
+	}
+	return res
+}
+
+// addRoute records the route on the root bundle.
+func (b *Bundle) addRoute(rt *route) {
+	root := b.rootBundle()
+	if root.index == nil {
+		root.index = make(map[string]*route)
+	}
+	root.routes = append(root.routes, rt)
+	if _, ok := root.index[rt.pattern]; !ok { // variants are found through the route registered with the mux
+		root.index[rt.pattern] = rt
+	}
+	if len(rt.skip) > 0 {
+		root.dispatch.Store(nil) // the route needs its own root chain
+	}
+}
+
+// globalMiddlewares returns the root bundle's middlewares, leaving out the skipped ones.
+func (b *Bundle) globalMiddlewares(skip []*middleware) []*middleware {
+	root := b.rootBundle()
+	if len(skip) == 0 {
+		return root.middlewares
+	}
+	res := make([]*middleware, 0, len(root.middlewares))
+	for _, mw := range root.middlewares {
+		if !slices.Contains(skip, mw) {
+			res = append(res, mw)
+		}
+	}
+	return res
+}
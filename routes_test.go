@@ -0,0 +1,270 @@
+package routegroup_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/go-pkgz/routegroup"
+)
+
+func passThrough(next http.Handler) http.Handler { return next }
+
+func TestRoutes(t *testing.T) {
+	ok := func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) }
+	always := func(*http.Request) bool { return true }
+
+	rtr := routegroup.New(http.NewServeMux())
+	rtr.Use(routegroup.Named("log", passThrough), routegroup.Named("auth", passThrough))
+	rtr.Group().HandleFunc("GET /health", ok)
+
+	api := rtr.Mount("/api")
+	api.Use(routegroup.Named("api", passThrough))
+	api.HandleFunc("GET /users/{id}", ok)
+	api.With(routegroup.Named("csrf", passThrough)).HandleFunc("POST /users", ok)
+	api.Without("auth").HandleFunc("/public", ok)
+	api.WithIf(always, passThrough).HandleFunc("DELETE /users/{id}", ok)
+	api.HandleFiles("/static", http.Dir("testdata"))
+	api.HandleRoot("GET", http.HandlerFunc(ok))
+
+	// root middleware added after child routes is part of all chains
+	rtr.Use(routegroup.Named("late", passThrough))
+
+	expected := []routegroup.RouteInfo{
+		{Pattern: "GET /health", Method: "GET", Path: "/health", Middlewares: []string{"log", "auth", "late"}},
+		{Pattern: "GET /api/users/{id}", Method: "GET", Path: "/api/users/{id}", Middlewares: []string{"log", "auth", "late", "api"}},
+		{Pattern: "POST /api/users", Method: "POST", Path: "/api/users", Middlewares: []string{"log", "auth", "late", "api", "csrf"}},
+		{Pattern: "/api/public", Path: "/api/public", Middlewares: []string{"log", "late", "api"}},
+		{Pattern: "DELETE /api/users/{id}", Method: "DELETE", Path: "/api/users/{id}",
+			Middlewares: []string{"log", "auth", "late", "api", "routegroup_test.passThrough (conditional)"}},
+		{Pattern: "/api/static/", Path: "/api/static/", Middlewares: []string{"log", "auth", "late", "api"}},
+		{Pattern: "GET /api", Method: "GET", Path: "/api", Middlewares: []string{"log", "auth", "late", "api"}},
+	}
+
+	// routes are the same no matter which bundle of the tree is asked
+	for _, b := range []*routegroup.Bundle{rtr, api} {
+		if got := b.Routes(); !reflect.DeepEqual(got, expected) {
+			t.Errorf("unexpected routes\nwant: %+v\ngot:  %+v", expected, got)
+		}
+	}
+}
+
+func TestRoutesDoNotCallMiddlewares(t *testing.T) {
+	ok := func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) }
+	var built atomic.Int32
+	counted := func(next http.Handler) http.Handler {
+		built.Add(1)
+		return next
+	}
+	// a middleware creating a wrapper specific to its next handler, panicking on anything else
+	strict := func(next http.Handler) http.Handler {
+		if _, ok := next.(http.HandlerFunc); !ok {
+			panic("unexpected next handler")
+		}
+		return next
+	}
+
+	rtr := routegroup.New(http.NewServeMux())
+	rtr.Require(routegroup.Policy{Middlewares: []string{"counted"}})
+	api := rtr.Mount("/api")
+	api.Use(routegroup.Named("counted", counted), strict)
+	api.WithIf(func(*http.Request) bool { return true }, counted).HandleFunc("GET /users", ok)
+	api.Without("counted").HandleFunc("GET /public", ok)
+	if built.Load() != 2 {
+		t.Fatalf("expected middlewares created once per route, got %d", built.Load())
+	}
+
+	routes := rtr.Routes()
+	if err := rtr.Validate(); err == nil {
+		t.Error("expected policy violation for the public route")
+	}
+	if err := rtr.Freeze(); err == nil {
+		t.Error("expected freeze to fail")
+	}
+	if built.Load() != 2 {
+		t.Errorf("expected no middleware calls by introspection, got %d calls", built.Load()-2)
+	}
+	if got := routes[0].Middlewares; !reflect.DeepEqual(got, []string{"counted", "routegroup_test.TestRoutesDoNotCallMiddlewares.func3",
+		"routegroup_test.TestRoutesDoNotCallMiddlewares.func2 (conditional)"}) {
+		t.Errorf("unexpected middlewares %v", got)
+	}
+}
+
+func TestRoutesEmpty(t *testing.T) {
+	rtr := routegroup.New(http.NewServeMux())
+	if routes := rtr.Routes(); len(routes) != 0 {
+		t.Errorf("expected no routes, got %+v", routes)
+	}
+
+	rtr.HandleFunc("/plain", func(http.ResponseWriter, *http.Request) {})
+	routes := rtr.Routes()
+	if len(routes) != 1 {
+		t.Fatalf("expected 1 route, got %d", len(routes))
+	}
+	if routes[0].Middlewares != nil {
+		t.Errorf("expected no middlewares, got %v", routes[0].Middlewares)
+	}
+}
+
+func TestRouteRuntimeState(t *testing.T) {
+	rtr := routegroup.New(http.NewServeMux())
+	rtr.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Root", "true")
+			next.ServeHTTP(w, r)
+		})
+	})
+	api := rtr.Mount("/api")
+	api.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Api", "true")
+			next.ServeHTTP(w, r)
+		})
+	})
+	api.HandleFunc("GET /items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("item " + r.PathValue("id")))
+	})
+	api.HandleFunc("GET /other", func(w http.ResponseWriter, _ *http.Request) { _, _ = w.Write([]byte("other")) })
+	if err := rtr.Freeze(); err != nil { // runtime changes are allowed on a frozen router
+		t.Fatal(err)
+	}
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		rtr.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, http.NoBody))
+		return rec
+	}
+	state := func(pattern string) routegroup.RouteInfo {
+		for _, ri := range rtr.Routes() {
+			if ri.Pattern == pattern {
+				return ri
+			}
+		}
+		t.Fatalf("route %q not found", pattern)
+		return routegroup.RouteInfo{}
+	}
+
+	t.Run("disable with 503", func(t *testing.T) {
+		if err := api.DisableRoute("GET /api/items/{id}", http.StatusServiceUnavailable); err != nil {
+			t.Fatal(err)
+		}
+		rec := get("/api/items/1")
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("expected 503, got %d", rec.Code)
+		}
+		if rec.Header().Get("X-Root") != "true" || rec.Header().Get("X-Api") != "" {
+			t.Errorf("expected root middleware only, got headers %v", rec.Header())
+		}
+		if rec = get("/api/other"); rec.Code != http.StatusOK {
+			t.Errorf("other route should not be affected, got %d", rec.Code)
+		}
+		ri := state("GET /api/items/{id}")
+		if ri.State != routegroup.RouteDisabled || ri.DisabledStatus != http.StatusServiceUnavailable {
+			t.Errorf("unexpected state %v/%d", ri.State, ri.DisabledStatus)
+		}
+		if ri.State.String() != "disabled" {
+			t.Errorf("unexpected state name %q", ri.State.String())
+		}
+	})
+
+	t.Run("enable", func(t *testing.T) {
+		if err := rtr.EnableRoute("GET /api/items/{id}"); err != nil {
+			t.Fatal(err)
+		}
+		rec := get("/api/items/2")
+		if rec.Code != http.StatusOK || rec.Body.String() != "item 2" {
+			t.Errorf("expected 200 'item 2', got %d %q", rec.Code, rec.Body.String())
+		}
+		if ri := state("GET /api/items/{id}"); ri.State != routegroup.RouteEnabled || ri.State.String() != "enabled" {
+			t.Errorf("unexpected state %v", ri.State)
+		}
+	})
+
+	t.Run("replace keeps group middlewares", func(t *testing.T) {
+		err := rtr.ReplaceRoute("GET /api/items/{id}", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("new item " + r.PathValue("id")))
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rec := get("/api/items/3")
+		if rec.Body.String() != "new item 3" {
+			t.Errorf("expected replaced handler, got %q", rec.Body.String())
+		}
+		if rec.Header().Get("X-Api") != "true" {
+			t.Error("expected group middleware applied to replaced handler")
+		}
+		if ri := state("GET /api/items/{id}"); !ri.Replaced {
+			t.Error("expected route marked as replaced")
+		}
+		if ri := state("GET /api/other"); ri.Replaced {
+			t.Error("other route should not be marked as replaced")
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		if err := rtr.DisableRoute("GET /nope", http.StatusNotFound); !errors.Is(err, routegroup.ErrUnknownRoute) {
+			t.Errorf("expected ErrUnknownRoute, got %v", err)
+		}
+		if err := rtr.EnableRoute("/nope"); !errors.Is(err, routegroup.ErrUnknownRoute) {
+			t.Errorf("expected ErrUnknownRoute, got %v", err)
+		}
+		if err := rtr.ReplaceRoute("/nope", http.NotFoundHandler()); !errors.Is(err, routegroup.ErrUnknownRoute) {
+			t.Errorf("expected ErrUnknownRoute, got %v", err)
+		}
+		if err := rtr.DisableRoute("GET /api/other", http.StatusOK); err == nil {
+			t.Error("expected error for non-error status")
+		}
+	})
+}
+
+func TestDisabledRouteUsesCustomNotFound(t *testing.T) {
+	rtr := routegroup.New(http.NewServeMux())
+	rtr.NotFoundHandler(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("custom 404"))
+	})
+	rtr.HandleFunc("GET /feature", func(w http.ResponseWriter, _ *http.Request) { _, _ = w.Write([]byte("feature")) })
+	if err := rtr.DisableRoute("GET /feature", http.StatusNotFound); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	rtr.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/feature", http.NoBody))
+	if rec.Code != http.StatusNotFound || rec.Body.String() != "custom 404" {
+		t.Errorf("expected custom 404, got %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestRouteToggleWhileServing(t *testing.T) {
+	rtr := routegroup.New(http.NewServeMux())
+	rtr.HandleFunc("GET /flag", func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) })
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			_ = rtr.DisableRoute("GET /flag", http.StatusServiceUnavailable)
+			_ = rtr.ReplaceRoute("GET /flag", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+			_ = rtr.EnableRoute("GET /flag")
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			rec := httptest.NewRecorder()
+			rtr.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/flag", http.NoBody))
+			if rec.Code != http.StatusOK && rec.Code != http.StatusServiceUnavailable {
+				t.Errorf("unexpected status %d", rec.Code)
+			}
+		}
+	}()
+	wg.Wait()
+}
@@ -0,0 +1,55 @@
+package routegroup
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// Switcher is an http.Handler serving requests with the current router, which can be replaced
+// as a whole at runtime, e.g. to reload route configuration without restarting the process.
+// In-flight requests finish on the router they started with.
+type Switcher struct {
+	current atomic.Pointer[Bundle]
+}
+
+// NewSwitcher makes a Switcher serving with the given router. The router is frozen, see Swap.
+// The router can be nil, then the Switcher responds with 503 until a router is set with Swap.
+func NewSwitcher(router *Bundle) (*Switcher, error) {
+	s := &Switcher{}
+	if router == nil {
+		return s, nil
+	}
+	if _, err := s.Swap(router); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// ServeHTTP serves the request with the current router.
+func (s *Switcher) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	router := s.current.Load()
+	if router == nil {
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
+	}
+	router.ServeHTTP(w, r)
+}
+
+// Swap replaces the current router with the new one and returns the previous router, nil if there was none.
+// The new router is expected to be built on its own fresh http.ServeMux. It is frozen before the swap,
+// so a router failing validation (see Freeze) is rejected with an error, keeping the current one in place.
+// Swapping in nil removes the current router, so the Switcher responds with 503 as if created without one.
+func (s *Switcher) Swap(router *Bundle) (*Bundle, error) {
+	if router == nil {
+		return s.current.Swap(nil), nil
+	}
+	if err := router.Freeze(); err != nil {
+		return nil, err
+	}
+	return s.current.Swap(router.rootBundle()), nil
+}
+
+// Current returns the router currently serving requests, nil if there is none.
+func (s *Switcher) Current() *Bundle {
+	return s.current.Load()
+}
@@ -0,0 +1,180 @@
+package routegroup_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-pkgz/routegroup"
+)
+
+func tenantRouter(version string) *routegroup.Bundle {
+	rtr := routegroup.New(http.NewServeMux())
+	rtr.HandleFunc("GET /version", func(w http.ResponseWriter, _ *http.Request) { _, _ = w.Write([]byte(version)) })
+	if version == "v2" {
+		rtr.HandleFunc("GET /new", func(w http.ResponseWriter, _ *http.Request) { _, _ = w.Write([]byte("new")) })
+	}
+	return rtr
+}
+
+func TestSwitcher(t *testing.T) {
+	get := func(h http.Handler, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, http.NoBody))
+		return rec
+	}
+
+	t.Run("swap routers", func(t *testing.T) {
+		v1 := tenantRouter("v1")
+		sw, err := routegroup.NewSwitcher(v1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rec := get(sw, "/version"); rec.Body.String() != "v1" {
+			t.Errorf("expected v1, got %q", rec.Body.String())
+		}
+		if rec := get(sw, "/new"); rec.Code != http.StatusNotFound {
+			t.Errorf("expected 404 for /new on v1, got %d", rec.Code)
+		}
+		if sw.Current() != v1 {
+			t.Error("expected v1 to be current")
+		}
+
+		v2 := tenantRouter("v2")
+		prev, err := sw.Swap(v2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if prev != v1 {
+			t.Error("expected v1 returned as previous router")
+		}
+		if rec := get(sw, "/version"); rec.Body.String() != "v2" {
+			t.Errorf("expected v2, got %q", rec.Body.String())
+		}
+		if rec := get(sw, "/new"); rec.Body.String() != "new" {
+			t.Errorf("expected new route on v2, got %q", rec.Body.String())
+		}
+
+		// swapped routers are frozen
+		defer func() {
+			if recover() == nil {
+				t.Error("expected panic registering on a swapped router")
+			}
+		}()
+		v2.HandleFunc("/late", func(http.ResponseWriter, *http.Request) {})
+	})
+
+	t.Run("empty switcher", func(t *testing.T) {
+		sw, err := routegroup.NewSwitcher(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rec := get(sw, "/version"); rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("expected 503, got %d", rec.Code)
+		}
+		if sw.Current() != nil {
+			t.Error("expected no current router")
+		}
+		prev, err := sw.Swap(tenantRouter("v1"))
+		if err != nil || prev != nil {
+			t.Errorf("expected no previous router and no error, got %v, %v", prev, err)
+		}
+		if rec := get(sw, "/version"); rec.Body.String() != "v1" {
+			t.Errorf("expected v1, got %q", rec.Body.String())
+		}
+
+		// swapping in nil goes back to 503
+		prev, err = sw.Swap(nil)
+		if err != nil || prev == nil {
+			t.Errorf("expected previous router and no error, got %v, %v", prev, err)
+		}
+		if rec := get(sw, "/version"); rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("expected 503 after swapping in nil, got %d", rec.Code)
+		}
+		if sw.Current() != nil {
+			t.Error("expected no current router")
+		}
+	})
+
+	t.Run("invalid router rejected", func(t *testing.T) {
+		sw, err := routegroup.NewSwitcher(tenantRouter("v1"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		bad := tenantRouter("v2")
+		bad.Require(routegroup.Policy{Middlewares: []string{"auth"}})
+		var verr *routegroup.ValidationError
+		if _, err = sw.Swap(bad); !errors.As(err, &verr) {
+			t.Fatalf("expected validation error, got %v", err)
+		}
+		if rec := get(sw, "/version"); rec.Body.String() != "v1" {
+			t.Errorf("expected v1 kept, got %q", rec.Body.String())
+		}
+		if _, err = routegroup.NewSwitcher(bad); err == nil {
+			t.Error("expected error for invalid initial router")
+		}
+	})
+
+	t.Run("in-flight requests finish on old router", func(t *testing.T) {
+		started, release := make(chan struct{}), make(chan struct{})
+		old := routegroup.New(http.NewServeMux())
+		old.HandleFunc("GET /slow", func(w http.ResponseWriter, _ *http.Request) {
+			close(started)
+			<-release
+			_, _ = w.Write([]byte("old"))
+		})
+		sw, err := routegroup.NewSwitcher(old)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		done := make(chan string)
+		go func() { done <- get(sw, "/slow").Body.String() }()
+		<-started
+		if _, err = sw.Swap(tenantRouter("v2")); err != nil {
+			t.Fatal(err)
+		}
+		close(release)
+		select {
+		case body := <-done:
+			if body != "old" {
+				t.Errorf("expected in-flight request served by old router, got %q", body)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("in-flight request not finished")
+		}
+		if rec := get(sw, "/slow"); rec.Code != http.StatusNotFound {
+			t.Errorf("expected 404 for /slow on new router, got %d", rec.Code)
+		}
+	})
+
+	t.Run("concurrent swaps and serving", func(t *testing.T) {
+		sw, err := routegroup.NewSwitcher(tenantRouter("v0"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				if _, err := sw.Swap(tenantRouter(fmt.Sprintf("v%d", i))); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				if rec := get(sw, "/version"); rec.Code != http.StatusOK {
+					t.Errorf("unexpected status %d", rec.Code)
+				}
+			}
+		}()
+		wg.Wait()
+	})
+}
@@ -0,0 +1,150 @@
+package routegroup
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// TimeoutOpts configures Timeout.
+type TimeoutOpts struct {
+	Timeout time.Duration // request timeout
+	Status  int           // response status on timeout, 503 by default, 504 is common too
+}
+
+// Timeout returns a new Group with a timeout for requests of routes registered on it. The request
+// context gets a deadline, and if the handler hasn't written anything when it expires, the client gets
+// the timeout status right away, rendered with the custom ErrorHandler if set. The handler keeps running
+// until it returns, it's expected to stop on context cancellation; its later writes are discarded
+// and return http.ErrHandlerTimeout. Unlike http.TimeoutHandler, the response is not buffered,
+// so flushing works and a handler which started writing before the deadline is not interrupted.
+// The middleware is named "timeout", so streaming routes can opt out with Without("timeout").
+func (b *Bundle) Timeout(opts TimeoutOpts) *Bundle {
+	if opts.Timeout <= 0 {
+		panic("routegroup: timeout must be positive")
+	}
+	if opts.Status == 0 {
+		opts.Status = http.StatusServiceUnavailable
+	}
+
+	mu := b.treeLock()
+	mu.Lock()
+	defer mu.Unlock()
+	nb := b.clone()
+	nb.middlewares = append(nb.middlewares, &middleware{fn: Named("timeout", func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), opts.Timeout)
+			defer cancel()
+			r = r.WithContext(ctx)
+
+			tw := &timeoutWriter{w: w, header: make(http.Header), done: make(chan struct{})}
+			stop := context.AfterFunc(ctx, func() {
+				defer close(tw.done)
+				if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+					return // canceled, e.g. the client went away
+				}
+				tw.timeout(func() {
+					nb.renderError(w, r, opts.Status)
+					_ = http.NewResponseController(w).Flush()
+				})
+			})
+			next.ServeHTTP(tw, r)
+			if !stop() {
+				<-tw.done // the timeout response is being written
+			}
+			tw.finish()
+		})
+	})})
+	return nb
+}
+
+// timeoutWriter passes the handler's response through unless the timeout response was written first.
+// The handler gets its own header map, copied to the response on the first write, as the timeout
+// response is written from another goroutine.
+type timeoutWriter struct {
+	w      http.ResponseWriter
+	header http.Header
+	done   chan struct{} // closed when the timeout callback finished
+
+	mu       sync.Mutex
+	wrote    bool
+	timedOut bool
+}
+
+func (tw *timeoutWriter) Header() http.Header { return tw.header }
+
+func (tw *timeoutWriter) WriteHeader(status int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return
+	}
+	tw.writeHeader()
+	if status >= 200 || status == http.StatusSwitchingProtocols {
+		tw.wrote = true
+	}
+	tw.w.WriteHeader(status)
+}
+
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	tw.writeHeader()
+	tw.wrote = true
+	return tw.w.Write(b)
+}
+
+// FlushError flushes the response unless it timed out. http.ResponseController uses it for Flush.
+func (tw *timeoutWriter) FlushError() error {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return http.ErrHandlerTimeout
+	}
+	tw.writeHeader()
+	tw.wrote = true
+	return http.NewResponseController(tw.w).Flush()
+}
+
+// Flush flushes the response unless it timed out.
+func (tw *timeoutWriter) Flush() { _ = tw.FlushError() }
+
+// Unwrap returns the wrapped writer for http.ResponseController.
+func (tw *timeoutWriter) Unwrap() http.ResponseWriter { return tw.w }
+
+// writeHeader copies the handler's header to the response before the first write.
+// Must be called with the lock held.
+func (tw *timeoutWriter) writeHeader() {
+	if tw.wrote {
+		return
+	}
+	h := tw.w.Header()
+	for k, v := range tw.header {
+		h[k] = v
+	}
+}
+
+// finish copies the header of a handler which returned without writing anything.
+func (tw *timeoutWriter) finish() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if !tw.timedOut {
+		tw.writeHeader()
+	}
+}
+
+// timeout writes the timeout response with fn if the handler hasn't written anything yet.
+func (tw *timeoutWriter) timeout(fn func()) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.wrote {
+		return
+	}
+	tw.timedOut = true
+	fn()
+}
@@ -0,0 +1,133 @@
+package routegroup_test
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-pkgz/routegroup"
+)
+
+func TestTimeout(t *testing.T) {
+	lateWrite := make(chan error, 1)
+	rtr := routegroup.New(http.NewServeMux())
+	api := rtr.Mount("/api").Timeout(routegroup.TimeoutOpts{Timeout: 50 * time.Millisecond})
+	api.HandleFunc("GET /fast", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("X-Fast", "yes")
+		_, _ = w.Write([]byte("fast"))
+	})
+	api.HandleFunc("GET /header-only", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("X-Only", "yes")
+	})
+	api.HandleFunc("GET /slow", func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		time.Sleep(10 * time.Millisecond) // still writing after the timeout response
+		w.Header().Set("X-Late", "yes")
+		_, err := w.Write([]byte("late"))
+		lateWrite <- err
+	})
+	api.HandleFunc("GET /stream", func(w http.ResponseWriter, _ *http.Request) {
+		for i := range 3 {
+			_, _ = w.Write([]byte{'a' + byte(i)})
+			_ = http.NewResponseController(w).Flush()
+			time.Sleep(40 * time.Millisecond) // longer than the timeout in total, but started writing
+		}
+	})
+	api.Without("timeout").HandleFunc("GET /long-stream", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(80 * time.Millisecond)
+		if r.Context().Err() != nil {
+			t.Error("opted out route has the deadline")
+		}
+		_, _ = w.Write([]byte("done"))
+	})
+	rtr.Mount("/gw").Timeout(routegroup.TimeoutOpts{Timeout: 10 * time.Millisecond, Status: http.StatusGatewayTimeout}).
+		HandleFunc("GET /slow", func(_ http.ResponseWriter, r *http.Request) { <-r.Context().Done() })
+
+	ts := httptest.NewServer(rtr)
+	defer ts.Close()
+	get := func(path string) (*http.Response, string) {
+		t.Helper()
+		resp, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp, string(body)
+	}
+
+	t.Run("fast", func(t *testing.T) {
+		resp, body := get("/api/fast")
+		if resp.StatusCode != 200 || body != "fast" || resp.Header.Get("X-Fast") != "yes" {
+			t.Errorf("unexpected response %d %q %v", resp.StatusCode, body, resp.Header)
+		}
+		resp, _ = get("/api/header-only")
+		if resp.StatusCode != 200 || resp.Header.Get("X-Only") != "yes" {
+			t.Errorf("unexpected response %d %v", resp.StatusCode, resp.Header)
+		}
+	})
+
+	t.Run("slow", func(t *testing.T) {
+		resp, body := get("/api/slow")
+		if resp.StatusCode != http.StatusServiceUnavailable || body != "Service Unavailable\n" || resp.Header.Get("X-Late") != "" {
+			t.Errorf("unexpected response %d %q %v", resp.StatusCode, body, resp.Header)
+		}
+		if err := <-lateWrite; !errors.Is(err, http.ErrHandlerTimeout) {
+			t.Errorf("expected ErrHandlerTimeout for late write, got %v", err)
+		}
+	})
+
+	t.Run("gateway timeout", func(t *testing.T) {
+		if resp, _ := get("/gw/slow"); resp.StatusCode != http.StatusGatewayTimeout {
+			t.Errorf("unexpected status %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("timeout response sent before handler returns", func(t *testing.T) {
+		start := time.Now()
+		resp, err := http.Get(ts.URL + "/api/slow")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusServiceUnavailable {
+			t.Errorf("unexpected status %d", resp.StatusCode)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("timeout response took %v", elapsed)
+		}
+		<-lateWrite
+	})
+
+	t.Run("streaming started before the deadline", func(t *testing.T) {
+		resp, err := http.Get(ts.URL + "/api/stream")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		br := bufio.NewReader(resp.Body)
+		if b, _ := br.ReadByte(); b != 'a' {
+			t.Errorf("first chunk not flushed, got %q", b)
+		}
+		rest, _ := io.ReadAll(br)
+		if resp.StatusCode != 200 || string(rest) != "bc" {
+			t.Errorf("unexpected response %d %q", resp.StatusCode, rest)
+		}
+	})
+
+	t.Run("opt out", func(t *testing.T) {
+		if resp, body := get("/api/long-stream"); resp.StatusCode != 200 || body != "done" {
+			t.Errorf("unexpected response %d %q", resp.StatusCode, body)
+		}
+		for _, ri := range rtr.Routes() {
+			if ri.Pattern == "GET /api/long-stream" && strings.Contains(strings.Join(ri.Middlewares, ","), "timeout") {
+				t.Errorf("unexpected middlewares %v", ri.Middlewares)
+			}
+		}
+	})
+}
@@ -0,0 +1,99 @@
+package routegroup
+
+import (
+	"context"
+	"net/http"
+)
+
+// Tracer creates spans for requests served by the bundle, see SetTracer. It is meant to be
+// implemented by a small adapter to a tracing library.
+type Tracer interface {
+	// Start starts a span with the given name, returning the context carrying the span and
+	// a function ending the span with the final response status.
+	Start(ctx context.Context, name string) (context.Context, func(status int))
+}
+
+// span names of requests without a matching route
+const (
+	SpanNotFound         = "NotFound"         // unmatched request answered by the mux with 404
+	SpanCustomNotFound   = "NotFoundHandler"  // unmatched request answered by the custom NotFoundHandler
+	SpanMethodNotAllowed = "MethodNotAllowed" // request matching a route path but not its method, answered with 405
+	SpanPreflight        = "Preflight"        // CORS preflight answered for a route with CORS enabled, see Bundle.CORS
+)
+
+// SetTracer sets the tracer called around each request served by the bundle's tree, nil to disable tracing.
+// Spans are named by the matched route pattern, e.g. "GET /api/users/{id}", by SpanNotFound,
+// SpanCustomNotFound or SpanMethodNotAllowed for unmatched requests and by SpanPreflight for CORS preflights. Spans wrap root middlewares,
+// so the request context passed to all middlewares and handlers carries the span, and they end
+// with the final response status, including the one written by the custom NotFoundHandler.
+func (b *Bundle) SetTracer(t Tracer) {
+	mu := b.treeLock()
+	mu.Lock()
+	defer mu.Unlock()
+	b.checkFrozen()
+	root := b.rootBundle()
+	root.tracer = t
+	root.dispatch.Store(nil) // the tracer is part of the composed dispatch
+}
+
+// serveTraced serves the request within a span named by the pattern.
+func (b *Bundle) serveTraced(w http.ResponseWriter, r *http.Request, d *dispatch, pattern string) {
+	name := pattern
+	if pattern == "" {
+		name = b.unmatchedSpan(r, d)
+	}
+	ctx, end := d.tracer.Start(r.Context(), name)
+	sw := NewResponseWriter(w)
+	defer func() {
+		if rec := recover(); rec != nil {
+			end(http.StatusInternalServerError)
+			panic(rec)
+		}
+		end(sw.Status())
+	}()
+	d.serve(sw, r.WithContext(ctx), pattern)
+}
+
+// unmatchedSpan returns the span name of a request without a matching route. The mux response is
+// probed to tell 405 from 404, as the mux doesn't report it otherwise.
+func (b *Bundle) unmatchedSpan(r *http.Request, d *dispatch) string {
+	if b.corsPreflight(r) {
+		return SpanPreflight
+	}
+	probe := &statusProbe{}
+	b.mux.ServeHTTP(probe, r)
+	switch {
+	case probe.status == http.StatusMethodNotAllowed:
+		return SpanMethodNotAllowed
+	case d.notFound != nil:
+		return SpanCustomNotFound
+	default:
+		return SpanNotFound
+	}
+}
+
+// statusProbe is a ResponseWriter discarding the response, keeping the status only.
+type statusProbe struct {
+	header http.Header
+	status int
+}
+
+func (p *statusProbe) Header() http.Header {
+	if p.header == nil {
+		p.header = http.Header{}
+	}
+	return p.header
+}
+
+func (p *statusProbe) Write(b []byte) (int, error) {
+	if p.status == 0 {
+		p.status = http.StatusOK
+	}
+	return len(b), nil
+}
+
+func (p *statusProbe) WriteHeader(status int) {
+	if p.status == 0 {
+		p.status = status
+	}
+}
@@ -0,0 +1,132 @@
+package routegroup_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/go-pkgz/routegroup"
+)
+
+type spanKey struct{}
+
+type testSpan struct {
+	name   string
+	status int
+}
+
+type testTracer struct {
+	mu    sync.Mutex
+	spans []*testSpan
+}
+
+func (t *testTracer) Start(ctx context.Context, name string) (context.Context, func(status int)) {
+	s := &testSpan{name: name}
+	t.mu.Lock()
+	t.spans = append(t.spans, s)
+	t.mu.Unlock()
+	return context.WithValue(ctx, spanKey{}, s), func(status int) { s.status = status }
+}
+
+func TestTracer(t *testing.T) {
+	newRouter := func(tr routegroup.Tracer) *routegroup.Bundle {
+		rtr := routegroup.New(http.NewServeMux())
+		rtr.SetTracer(tr)
+		rtr.Use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if _, ok := r.Context().Value(spanKey{}).(*testSpan); !ok && tr != nil {
+					t.Error("root middleware has no span in context")
+				}
+				next.ServeHTTP(w, r)
+			})
+		})
+		rtr.Mount("/api").HandleFunc("POST /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+			if s, ok := r.Context().Value(spanKey{}).(*testSpan); !ok || s.name != "POST /api/users/{id}" {
+				t.Errorf("handler has no span in context: %v", s)
+			}
+			w.WriteHeader(http.StatusCreated)
+		})
+		return rtr
+	}
+
+	t.Run("span names and statuses", func(t *testing.T) {
+		tr := &testTracer{}
+		rtr := newRouter(tr)
+		for _, req := range []struct{ method, path string }{{"POST", "/api/users/1"}, {"GET", "/api/users/1"}, {"GET", "/nope"}} {
+			rtr.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(req.method, req.path, http.NoBody))
+		}
+		want := []testSpan{
+			{"POST /api/users/{id}", http.StatusCreated},
+			{routegroup.SpanMethodNotAllowed, http.StatusMethodNotAllowed},
+			{routegroup.SpanNotFound, http.StatusNotFound},
+		}
+		if len(tr.spans) != len(want) {
+			t.Fatalf("expected %d spans, got %d", len(want), len(tr.spans))
+		}
+		for i, w := range want {
+			if *tr.spans[i] != w {
+				t.Errorf("span %d: got %+v, want %+v", i, *tr.spans[i], w)
+			}
+		}
+	})
+
+	t.Run("custom not found", func(t *testing.T) {
+		tr := &testTracer{}
+		rtr := newRouter(tr)
+		rtr.NotFoundHandler(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusGone) })
+		rec := httptest.NewRecorder()
+		rtr.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/nope", http.NoBody))
+		rtr.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/users/1", http.NoBody))
+		if rec.Code != http.StatusGone {
+			t.Errorf("expected custom handler response, got %d", rec.Code)
+		}
+		if len(tr.spans) != 2 || *tr.spans[0] != (testSpan{routegroup.SpanCustomNotFound, http.StatusGone}) ||
+			*tr.spans[1] != (testSpan{routegroup.SpanMethodNotAllowed, http.StatusMethodNotAllowed}) {
+			t.Errorf("unexpected spans %+v %+v", tr.spans[0], tr.spans[1])
+		}
+	})
+
+	t.Run("cors preflight", func(t *testing.T) {
+		tr := &testTracer{}
+		rtr := routegroup.New(http.NewServeMux())
+		rtr.SetTracer(tr)
+		rtr.CORS(routegroup.CORSOpts{AllowedOrigins: []string{"*"}}).HandleFunc("PUT /users/{id}", func(http.ResponseWriter, *http.Request) {})
+		rtr.HandleFunc("/", func(http.ResponseWriter, *http.Request) { t.Error("catch-all called for preflight") })
+		req := httptest.NewRequest(http.MethodOptions, "/users/1", http.NoBody)
+		req.Header.Set("Origin", "https://app.example.com")
+		req.Header.Set("Access-Control-Request-Method", "PUT")
+		rtr.ServeHTTP(httptest.NewRecorder(), req)
+		if len(tr.spans) != 1 || *tr.spans[0] != (testSpan{routegroup.SpanPreflight, http.StatusNoContent}) {
+			t.Errorf("unexpected spans %+v", tr.spans)
+		}
+	})
+
+	t.Run("panic ends span", func(t *testing.T) {
+		tr := &testTracer{}
+		rtr := routegroup.New(http.NewServeMux())
+		rtr.SetTracer(tr)
+		rtr.HandleFunc("GET /panic", func(http.ResponseWriter, *http.Request) { panic("boom") })
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Error("expected panic")
+				}
+			}()
+			rtr.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/panic", http.NoBody))
+		}()
+		if len(tr.spans) != 1 || tr.spans[0].status != http.StatusInternalServerError {
+			t.Errorf("unexpected spans %v", tr.spans)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		tr := &testTracer{}
+		rtr := newRouter(nil)
+		rtr.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/nope", http.NoBody))
+		if len(tr.spans) != 0 {
+			t.Errorf("unexpected spans %v", tr.spans)
+		}
+	})
+}
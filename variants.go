@@ -0,0 +1,123 @@
+package routegroup
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// selector constrains a route to requests it accepts, beyond what the mux matches by method, host and path.
+// Routes with selectors can share a pattern, the mux dispatches to the first registered one, which picks
+// the variant serving the request, see route.selectVariant.
+type selector struct {
+	key   string                    // describes the constraint, e.g. "version v2", to detect duplicate variants
+	match func(r *http.Request) int // returns 0 if the request is accepted, or the status for a mismatch
+	vary  func(h http.Header)       // adds request headers the choice depends on to the Vary header, optional
+
+	// quality ranks variants accepting the request, e.g. by the Accept header, 1 if not set.
+	quality func(r *http.Request) float64
+}
+
+// variantKey describes the constraints of the route, empty for a route without selectors.
+func (rt *route) variantKey() string {
+	keys := make([]string, 0, len(rt.selectors))
+	for _, s := range rt.selectors {
+		keys = append(keys, s.key)
+	}
+	return strings.Join(keys, ", ")
+}
+
+// addVariant adds the route as a variant of rt, the route registered with the mux for the same pattern.
+// Constrained variants are tried in registration order, an unconstrained one is the fallback.
+// Must be called with the tree lock held.
+func (rt *route) addVariant(v *route) {
+	variants := rt.variants.Load()
+	if variants == nil {
+		variants = &[]*route{rt}
+	}
+	key := v.variantKey()
+	for _, existing := range *variants {
+		if existing.variantKey() == key {
+			panic(fmt.Sprintf("routegroup: duplicate route %q with the same constraints", v.pattern))
+		}
+	}
+	if !slices.Equal(v.skip, rt.skip) {
+		panic(fmt.Sprintf("routegroup: routes for %q must exclude the same root middlewares", v.pattern))
+	}
+	res := make([]*route, 0, len(*variants)+1)
+	res = append(res, *variants...)
+	res = append(res, v)
+	slices.SortStableFunc(res, func(a, b *route) int {
+		// routes without selectors go last
+		switch {
+		case len(a.selectors) > 0 && len(b.selectors) == 0:
+			return -1
+		case len(a.selectors) == 0 && len(b.selectors) > 0:
+			return 1
+		}
+		return 0
+	})
+	rt.variants.Store(&res)
+}
+
+// selectVariant returns the variant serving the request. Of several variants accepting the request,
+// the one with the highest quality wins, the first registered one for equal qualities, and a variant
+// without selectors serves the request only if no constrained one does. If no variant accepts the request,
+// it returns the status of the variant failing the latest, i.e. the one passing most selectors.
+func (rt *route) selectVariant(w http.ResponseWriter, r *http.Request) (*route, int) {
+	variants := []*route{rt}
+	if v := rt.variants.Load(); v != nil {
+		variants = *v
+	}
+	for _, v := range variants {
+		for _, s := range v.selectors {
+			if s.vary != nil {
+				s.vary(w.Header())
+			}
+		}
+	}
+
+	var best *route
+	bestQuality, status, passed := 0.0, http.StatusNotFound, -1
+	for _, v := range variants {
+		if len(v.selectors) == 0 && best != nil {
+			break // fallback, constrained variants go first
+		}
+		n, failed, quality := 0, 0, 1.0
+		for _, s := range v.selectors {
+			if failed = s.match(r); failed != 0 {
+				break
+			}
+			if s.quality != nil {
+				quality *= s.quality(r)
+			}
+			n++
+		}
+		if failed != 0 {
+			if n > passed {
+				status, passed = failed, n
+			}
+			continue
+		}
+		if best == nil || quality > bestQuality {
+			best, bestQuality = v, quality
+		}
+	}
+	if best != nil {
+		return best, 0
+	}
+	return nil, status
+}
+
+// addVary adds the request header to the Vary header of the response, unless already listed.
+func addVary(h http.Header, name string) {
+	for _, v := range h.Values("Vary") {
+		for _, field := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(field), name) {
+				return
+			}
+		}
+	}
+	h.Add("Vary", name)
+}
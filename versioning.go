@@ -0,0 +1,136 @@
+package routegroup
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// DefaultVersionHeader is the request header selecting the API version, see Versioning.
+const DefaultVersionHeader = "API-Version"
+
+// VersioningOpts configures how the API version of a request is selected, see Bundle.Versioning.
+type VersioningOpts struct {
+	Header string // request header with the version, e.g. "API-Version: v2", DefaultVersionHeader if empty
+
+	// MediaType is the vendor media type prefix for versions requested with the Accept header,
+	// e.g. "application/vnd.example" for "Accept: application/vnd.example.v2+json". Disabled if empty.
+	MediaType string
+
+	// Default is the version served for requests not selecting one. Without it such requests get 404,
+	// unless a route without version is registered for the same pattern.
+	Default string
+}
+
+// Versioning sets how the bundle's tree selects the API version of requests to routes registered on
+// Version groups. It applies to all version groups of the tree and may be called before or after they are
+// registered.
+func (b *Bundle) Versioning(opts VersioningOpts) {
+	if opts.Header == "" {
+		opts.Header = DefaultVersionHeader
+	}
+	mu := b.treeLock()
+	mu.Lock()
+	defer mu.Unlock()
+	b.checkFrozen()
+	root := b.rootBundle()
+	root.versioning = opts
+	root.dispatch.Store(nil) // versioning is part of the composed dispatch
+}
+
+// Version returns a new Group for the API version, e.g. "v2". Each route registered on it is served
+// both with the version path prefix, as with Mount, e.g. "/api/v2/users" for "/users" on a group mounted
+// at "/api", and at the unprefixed path, e.g. "/api/users", for requests selecting the version with
+// the header or the vendor media type set with Versioning, or with no version if it is the default.
+// This lets several versions serve the same paths side by side. A request selecting a version not registered
+// for the path gets 406 if selected with the media type and 404 otherwise. A route registered on
+// the bundle itself, without version, serves requests to the path not matching any version.
+// Versions are compared ignoring case and the "v" prefix, so "v2", "V2" and "2" are the same version.
+// Routes registered with HandleFiles and HandleRoot are not versioned.
+func (b *Bundle) Version(version string) *Bundle {
+	if version == "" || strings.ContainsAny(version, "/{} ") {
+		panic(fmt.Sprintf("routegroup: invalid version %q", version))
+	}
+	mu := b.treeLock()
+	mu.Lock()
+	defer mu.Unlock()
+	if b.version != "" {
+		panic(fmt.Sprintf("routegroup: version %q inside version %q", version, b.version))
+	}
+	nb := b.clone()
+	nb.version, nb.versionBase = version, b.basePath
+	return nb
+}
+
+// handleVersion registers the route of a Version group with the version path prefix, and for
+// the unprefixed pattern with the version selector. Both routes are reported with the version.
+func (b *Bundle) handleVersion(prefixed, unprefixed string, handler http.Handler) {
+	b.handle(prefixed, handler).version = b.version
+	b.handle(unprefixed, handler, b.versionSelector()).version = b.version
+}
+
+// versionPath returns the base path of the bundle with the version prefix, e.g. "/api/v2/users"
+// for the version group mounted at "/api" and then at "/users".
+func (b *Bundle) versionPath() string {
+	return b.versionBase + "/" + b.version + strings.TrimPrefix(b.basePath, b.versionBase)
+}
+
+// versionSelector returns the selector accepting requests for the bundle's version.
+func (b *Bundle) versionSelector() selector {
+	root, version := b.rootBundle(), normalizeVersion(b.version)
+	return selector{
+		key: "version " + version,
+		match: func(r *http.Request) int {
+			requested, status := root.dispatcher().versioning.requested(r)
+			if requested != "" && normalizeVersion(requested) == version {
+				return 0
+			}
+			return status
+		},
+		vary: func(h http.Header) {
+			opts := root.dispatcher().versioning
+			addVary(h, opts.header())
+			if opts.MediaType != "" {
+				addVary(h, "Accept")
+			}
+		},
+	}
+}
+
+// requested returns the version selected by the request and the status for a request to a path
+// not served in it: the header first, then the Accept media types, then the default version.
+func (o VersioningOpts) requested(r *http.Request) (version string, status int) {
+	if v := strings.TrimSpace(r.Header.Get(o.header())); v != "" {
+		return v, http.StatusNotFound
+	}
+	if o.MediaType != "" {
+		prefix := strings.ToLower(o.MediaType) + "."
+		for _, accept := range r.Header.Values("Accept") {
+			for _, mediaRange := range strings.Split(accept, ",") {
+				mt, _, _ := strings.Cut(mediaRange, ";")
+				mt = strings.ToLower(strings.TrimSpace(mt))
+				if !strings.HasPrefix(mt, prefix) {
+					continue
+				}
+				v, _, _ := strings.Cut(mt[len(prefix):], "+") // "v2+json" or "v2"
+				if v != "" {
+					return v, http.StatusNotAcceptable
+				}
+			}
+		}
+	}
+	return o.Default, http.StatusNotFound
+}
+
+// header returns the version header name.
+func (o VersioningOpts) header() string {
+	if o.Header == "" {
+		return DefaultVersionHeader
+	}
+	return o.Header
+}
+
+// normalizeVersion returns the version in lower case without the "v" prefix.
+func normalizeVersion(v string) string {
+	return strings.TrimPrefix(strings.ToLower(v), "v")
+}
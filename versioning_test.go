@@ -0,0 +1,172 @@
+package routegroup_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-pkgz/routegroup"
+)
+
+func TestVersion(t *testing.T) {
+	reply := func(s string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) { _, _ = w.Write([]byte(s + " " + r.PathValue("id"))) }
+	}
+
+	rtr := routegroup.New(http.NewServeMux())
+	rtr.Versioning(routegroup.VersioningOpts{MediaType: "application/vnd.example", Default: "v1"})
+	api := rtr.Mount("/api")
+	v1 := api.Version("v1")
+	v1.HandleFunc("GET /users/{id}", reply("v1"))
+	v1.HandleFunc("GET /legacy", reply("legacy"))
+	v2 := api.Version("v2")
+	v2.HandleFunc("GET /users/{id}", reply("v2"))
+	v2.Mount("/admin").HandleFunc("GET /stats", reply("stats"))
+	v2.HandleFiles("/static", http.Dir("."))
+	v2.HandleRoot("GET", reply("root"))
+	api.HandleFunc("GET /health", reply("health"))
+
+	tbl := []struct {
+		name, path string
+		header     http.Header
+		code       int
+		body       string
+	}{
+		{name: "path v1", path: "/api/v1/users/1", code: http.StatusOK, body: "v1 1"},
+		{name: "path v2", path: "/api/v2/users/2", code: http.StatusOK, body: "v2 2"},
+		{name: "path nested mount", path: "/api/v2/admin/stats", code: http.StatusOK, body: "stats "},
+		{name: "path unknown", path: "/api/v3/users/1", code: http.StatusNotFound},
+		{name: "default", path: "/api/users/1", code: http.StatusOK, body: "v1 1"},
+		{name: "header v2", path: "/api/users/1", header: http.Header{"Api-Version": {"v2"}}, code: http.StatusOK, body: "v2 1"},
+		{name: "header without prefix", path: "/api/users/1", header: http.Header{"Api-Version": {"2"}}, code: http.StatusOK,
+			body: "v2 1"},
+		{name: "header unknown", path: "/api/users/1", header: http.Header{"Api-Version": {"v3"}}, code: http.StatusNotFound},
+		{name: "header missing in version", path: "/api/legacy", header: http.Header{"Api-Version": {"v2"}},
+			code: http.StatusNotFound},
+		{name: "media type", path: "/api/users/1", header: http.Header{"Accept": {"text/html, application/vnd.example.v2+json"}},
+			code: http.StatusOK, body: "v2 1"},
+		{name: "media type unknown", path: "/api/users/1", header: http.Header{"Accept": {"application/vnd.example.v3+json"}},
+			code: http.StatusNotAcceptable},
+		{name: "header wins", path: "/api/users/1",
+			header: http.Header{"Api-Version": {"v1"}, "Accept": {"application/vnd.example.v2+json"}}, code: http.StatusOK, body: "v1 1"},
+		{name: "files not versioned", path: "/api/static/", header: http.Header{"Api-Version": {"v7"}}, code: http.StatusOK},
+		{name: "root not versioned", path: "/api", header: http.Header{"Api-Version": {"v7"}}, code: http.StatusOK, body: "root "},
+		{name: "unversioned route", path: "/api/health", header: http.Header{"Api-Version": {"v2"}}, code: http.StatusOK,
+			body: "health "},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, http.NoBody)
+			for k, v := range tt.header {
+				req.Header[k] = v
+			}
+			rec := httptest.NewRecorder()
+			rtr.ServeHTTP(rec, req)
+			if rec.Code != tt.code {
+				t.Errorf("expected status %d, got %d", tt.code, rec.Code)
+			}
+			if tt.body != "" && rec.Body.String() != tt.body {
+				t.Errorf("expected body %q, got %q", tt.body, rec.Body.String())
+			}
+		})
+	}
+
+	t.Run("vary", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		rtr.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/users/1", http.NoBody))
+		if vary := rec.Header().Values("Vary"); len(vary) != 2 || vary[0] != "API-Version" || vary[1] != "Accept" {
+			t.Errorf("unexpected Vary %v", vary)
+		}
+	})
+
+	t.Run("routes", func(t *testing.T) {
+		var got []string
+		for _, ri := range rtr.Routes() {
+			got = append(got, ri.Pattern+" "+ri.Version)
+		}
+		want := []string{"GET /api/v1/users/{id} v1", "GET /api/users/{id} v1", "GET /api/v1/legacy v1", "GET /api/legacy v1",
+			"GET /api/v2/users/{id} v2", "GET /api/users/{id} v2", "GET /api/v2/admin/stats v2", "GET /api/admin/stats v2",
+			"/api/static/ ", "GET /api ", "GET /api/health "}
+		if len(got) != len(want) {
+			t.Fatalf("unexpected routes %v", got)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("route %d: expected %q, got %q", i, want[i], got[i])
+			}
+		}
+	})
+
+	t.Run("disable", func(t *testing.T) {
+		if err := rtr.DisableRoute("GET /api/users/{id}", http.StatusServiceUnavailable); err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = rtr.EnableRoute("GET /api/users/{id}") }()
+		req := httptest.NewRequest(http.MethodGet, "/api/users/1", http.NoBody)
+		req.Header.Set("API-Version", "v2")
+		rec := httptest.NewRecorder()
+		rtr.ServeHTTP(rec, req)
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("expected 503, got %d", rec.Code)
+		}
+	})
+}
+
+func TestVersionWithoutDefault(t *testing.T) {
+	ok := func(w http.ResponseWriter, _ *http.Request) { _, _ = w.Write([]byte("ok")) }
+	rtr := routegroup.New(http.NewServeMux())
+	rtr.NotFoundHandler(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("custom 404"))
+	})
+	rtr.Version("v1").HandleFunc("GET /users", ok)
+	rtr.Version("v2").HandleFunc("GET /users", ok)
+
+	rec := httptest.NewRecorder()
+	rtr.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users", http.NoBody))
+	if rec.Code != http.StatusNotFound || rec.Body.String() != "custom 404" {
+		t.Errorf("expected custom 404, got %d %q", rec.Code, rec.Body.String())
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/users", http.NoBody)
+	req.Header.Set("API-Version", "V2")
+	rec = httptest.NewRecorder()
+	rtr.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d", rec.Code)
+	}
+}
+
+func TestVersionPanics(t *testing.T) {
+	ok := func(http.ResponseWriter, *http.Request) {}
+	expectPanic := func(t *testing.T, fn func()) {
+		t.Helper()
+		defer func() {
+			if recover() == nil {
+				t.Error("expected panic")
+			}
+		}()
+		fn()
+	}
+
+	t.Run("invalid version", func(t *testing.T) {
+		expectPanic(t, func() { routegroup.New(http.NewServeMux()).Version("v1/beta") })
+	})
+
+	t.Run("nested version", func(t *testing.T) {
+		expectPanic(t, func() { routegroup.New(http.NewServeMux()).Version("v1").Version("v2") })
+	})
+
+	t.Run("duplicate version route", func(t *testing.T) {
+		rtr := routegroup.New(http.NewServeMux())
+		rtr.Version("v1").HandleFunc("GET /users", ok)
+		expectPanic(t, func() { rtr.Version("V1").HandleFunc("GET /users", ok) })
+	})
+
+	t.Run("different root middlewares", func(t *testing.T) {
+		rtr := routegroup.New(http.NewServeMux())
+		rtr.Use(routegroup.Named("auth", func(next http.Handler) http.Handler { return next }))
+		rtr.Version("v1").HandleFunc("GET /users", ok)
+		expectPanic(t, func() { rtr.Without("auth").Version("v2").HandleFunc("GET /users", ok) })
+	})
+}